@@ -8,9 +8,11 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/farseer-go/collections"
+	"github.com/farseer-go/fs/parse"
 )
 
 const defaultDockerHost = "unix:///var/run/docker.sock"
@@ -25,9 +27,11 @@ type dockerEndpoint struct {
 }
 
 type dockerAPI struct {
-	httpClient *http.Client
-	endpoint   dockerEndpoint
-	initErr    error
+	httpClient    *http.Client
+	endpoint      dockerEndpoint
+	initErr       error
+	credentials   CredentialProvider // 仓库认证信息
+	credentialsMu sync.RWMutex
 }
 
 func (receiver *dockerAPI) URL(apiPath string) string {
@@ -54,30 +58,38 @@ func (receiver errorTransport) RoundTrip(*http.Request) (*http.Response, error)
 // Client docker client
 type Client struct {
 	//dockerClient *client.Client
-	Container container
-	Service   service
-	Node      node
-	Hub       hub
-	Images    images
-	Event     event
-	api       *dockerAPI
-	Task      task
-	Config    config
+	Container  container
+	Service    service
+	Node       node
+	Hub        hub
+	Images     images
+	Event      event
+	api        *dockerAPI
+	Task       task
+	Config     config
+	Secret     secret
+	Credential credential
+	Registry   registry
+	Stack      stack
 }
 
 // NewClient 实例化一个Client
 func NewClient() *Client {
 	api := newDockerAPI(os.Getenv("DOCKER_HOST"))
 	client := &Client{
-		api:       api,
-		Container: container{api: api},
-		Service:   service{api: api},
-		Node:      node{api: api},
-		Hub:       hub{api: api},
-		Images:    images{api: api},
-		Event:     event{api: api},
-		Task:      task{api: api},
-		Config:    config{api: api},
+		api:        api,
+		Container:  container{api: api},
+		Service:    service{api: api},
+		Node:       node{api: api},
+		Hub:        hub{api: api},
+		Images:     images{api: api},
+		Event:      event{api: api},
+		Task:       task{api: api},
+		Config:     config{api: api},
+		Secret:     secret{api: api},
+		Credential: credential{api: api},
+		Registry:   newRegistry(api),
+		Stack:      stack{api: api},
 	}
 	return client
 }
@@ -86,15 +98,17 @@ func newDockerAPI(rawHost string) *dockerAPI {
 	endpoint, err := parseDockerHost(rawHost)
 	if err != nil {
 		return &dockerAPI{
-			httpClient: &http.Client{Transport: errorTransport{err: err}},
-			endpoint:   endpoint,
-			initErr:    err,
+			httpClient:  &http.Client{Transport: errorTransport{err: err}},
+			endpoint:    endpoint,
+			initErr:     err,
+			credentials: NewDockerConfigCredentialStore(""),
 		}
 	}
 
 	return &dockerAPI{
-		httpClient: newHTTPClient(endpoint),
-		endpoint:   endpoint,
+		httpClient:  newHTTPClient(endpoint),
+		endpoint:    endpoint,
+		credentials: NewDockerConfigCredentialStore(""),
 	}
 }
 
@@ -228,35 +242,40 @@ func (receiver *Client) GetInfo() DockerInfo {
 	return apiData
 }
 
-// SyncConfig 检查并更新服务的配置版本
+// SyncConfig 检查并更新服务的配置版本，只更新挂载到 targetConfigPath 的 <appName>_config_vN
 // 返回值：是否更新了配置
+//
+// Deprecated: 需要同步服务挂载的所有版本化配置、密钥并获取失败原因时，使用 SyncFiles
 func (receiver *Client) SyncConfig(appName string, targetConfigPath string) bool {
-	appVer, err := receiver.Service.GetCurConfigVersion(appName)
+	svc, err := receiver.Service.Inspect(appName)
 	if err != nil {
 		return false
 	}
+	// 服务当前挂载到 targetConfigPath 的配置版本
+	appVer := 0
+	for _, item := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+		if version, found := strings.CutPrefix(item.ConfigName, appName+"_config_v"); found && item.File.Name == targetConfigPath {
+			appVer = parse.ToInt(version)
+			break
+		}
+	}
 	// 说明服务没有使用配置
 	if appVer == 0 {
 		return false
 	}
 
-	// 获取服务当前使用的配置
-	configVersion, err := receiver.Config.GetLastVersion(appName)
-	if err != nil {
-		return false
-	}
-
-	// 没有读取到配置文件,则退出
-	if configVersion.Version == 0 {
+	// 获取最新版本的配置，没有读取到配置则退出
+	versions, err := receiver.Config.Versions(appName)
+	if err != nil || versions.Count() == 0 || versions.First().Version == 0 {
 		return false
 	}
+	configVersion := versions.First()
 
 	// 如果版本不一致，更新配置
 	if configVersion.Version != appVer {
 		isUpdate, _ := receiver.Service.UpdateServiceConfig(appName, configVersion.ID, configVersion.Spec.Name, targetConfigPath)
 		return isUpdate
 	}
-
 	return false
 }
 
@@ -0,0 +1,1108 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StackNamespaceLabel 标记服务、网络、配置、密钥所属的 stack
+const StackNamespaceLabel = "com.docker.stack.namespace"
+
+// stackImageLabel 部署时使用的镜像（与 docker stack deploy 保持一致）
+const stackImageLabel = "com.docker.stack.image"
+
+// ComposeFile docker-compose v3 文件
+type ComposeFile struct {
+	Version    string                       `yaml:"version,omitempty"`
+	Services   map[string]ComposeService    `yaml:"services"`
+	Networks   map[string]ComposeNetwork    `yaml:"networks,omitempty"`
+	Volumes    map[string]ComposeVolume     `yaml:"volumes,omitempty"`
+	Configs    map[string]ComposeFileObject `yaml:"configs,omitempty"`
+	Secrets    map[string]ComposeFileObject `yaml:"secrets,omitempty"`
+	WorkingDir string                       `yaml:"-"` // env_file、bind、configs.file 等相对路径的基准目录
+	Env        map[string]string            `yaml:"-"` // 变量插值、configs/secrets 的 environment 使用的环境变量，为 nil 时读取当前进程的环境变量
+}
+
+// ComposeService services 节点
+type ComposeService struct {
+	Image           string                   `yaml:"image"`
+	Entrypoint      ComposeCommand           `yaml:"entrypoint,omitempty"` // 对应 ContainerSpec.Command
+	Command         ComposeCommand           `yaml:"command,omitempty"`    // 对应 ContainerSpec.Args
+	Environment     ComposeMapping           `yaml:"environment,omitempty"`
+	EnvFile         ComposeStringList        `yaml:"env_file,omitempty"`
+	Labels          ComposeMapping           `yaml:"labels,omitempty"` // 容器标签
+	Hostname        string                   `yaml:"hostname,omitempty"`
+	User            string                   `yaml:"user,omitempty"`
+	WorkingDir      string                   `yaml:"working_dir,omitempty"`
+	Ports           ComposePorts             `yaml:"ports,omitempty"`
+	Volumes         []ComposeServiceVolume   `yaml:"volumes,omitempty"`
+	Networks        ComposeServiceNetworks   `yaml:"networks,omitempty"`
+	Configs         []ComposeServiceFile     `yaml:"configs,omitempty"`
+	Secrets         []ComposeServiceFile     `yaml:"secrets,omitempty"`
+	Deploy          ComposeDeploy            `yaml:"deploy,omitempty"`
+	Healthcheck     *ComposeHealthcheck      `yaml:"healthcheck,omitempty"`
+	Logging         *ComposeLogging          `yaml:"logging,omitempty"`
+	StopGracePeriod string                   `yaml:"stop_grace_period,omitempty"` // 10s
+	StopSignal      string                   `yaml:"stop_signal,omitempty"`
+	ExtraHosts      ComposeStringList        `yaml:"extra_hosts,omitempty"` // host:ip
+	DNS             ComposeStringList        `yaml:"dns,omitempty"`
+	DNSSearch       ComposeStringList        `yaml:"dns_search,omitempty"`
+	Init            *bool                    `yaml:"init,omitempty"`
+	ReadOnly        bool                     `yaml:"read_only,omitempty"`
+	TTY             bool                     `yaml:"tty,omitempty"`
+	StdinOpen       bool                     `yaml:"stdin_open,omitempty"`
+	CapAdd          []string                 `yaml:"cap_add,omitempty"`
+	CapDrop         []string                 `yaml:"cap_drop,omitempty"`
+	Sysctls         ComposeMapping           `yaml:"sysctls,omitempty"`
+	Ulimits         map[string]ComposeUlimit `yaml:"ulimits,omitempty"`
+}
+
+// ComposeDeploy deploy 节点
+type ComposeDeploy struct {
+	Mode           string                `yaml:"mode,omitempty"` // replicated global replicated-job global-job
+	Replicas       *int                  `yaml:"replicas,omitempty"`
+	MaxConcurrent  *int                  `yaml:"max_concurrent,omitempty"` // replicated-job 同时运行的任务数，默认与 replicas 相同
+	Labels         ComposeMapping        `yaml:"labels,omitempty"`         // 服务标签
+	EndpointMode   string                `yaml:"endpoint_mode,omitempty"`
+	Placement      ComposePlacement      `yaml:"placement,omitempty"`
+	Resources      ComposeResources      `yaml:"resources,omitempty"`
+	RestartPolicy  *ComposeRestartPolicy `yaml:"restart_policy,omitempty"`
+	UpdateConfig   *ComposeUpdateConfig  `yaml:"update_config,omitempty"`
+	RollbackConfig *ComposeUpdateConfig  `yaml:"rollback_config,omitempty"`
+}
+
+type ComposePlacement struct {
+	Constraints []string                     `yaml:"constraints,omitempty"`
+	Preferences []ComposePlacementPreference `yaml:"preferences,omitempty"`
+	MaxReplicas int                          `yaml:"max_replicas_per_node,omitempty"`
+}
+
+type ComposePlacementPreference struct {
+	Spread string `yaml:"spread"`
+}
+
+type ComposeResources struct {
+	Limits       ComposeResource `yaml:"limits,omitempty"`
+	Reservations ComposeResource `yaml:"reservations,omitempty"`
+}
+
+type ComposeResource struct {
+	CPUs   string `yaml:"cpus,omitempty"`   // 0.5
+	Memory string `yaml:"memory,omitempty"` // 512M
+	Pids   int64  `yaml:"pids,omitempty"`
+}
+
+type ComposeRestartPolicy struct {
+	Condition   string `yaml:"condition,omitempty"` // none on-failure any
+	Delay       string `yaml:"delay,omitempty"`
+	MaxAttempts int    `yaml:"max_attempts,omitempty"`
+	Window      string `yaml:"window,omitempty"`
+}
+
+type ComposeUpdateConfig struct {
+	Parallelism     *int    `yaml:"parallelism,omitempty"`
+	Delay           string  `yaml:"delay,omitempty"`
+	FailureAction   string  `yaml:"failure_action,omitempty"`
+	Monitor         string  `yaml:"monitor,omitempty"`
+	MaxFailureRatio float64 `yaml:"max_failure_ratio,omitempty"`
+	Order           string  `yaml:"order,omitempty"`
+}
+
+type ComposeHealthcheck struct {
+	Test          ComposeHealthTest `yaml:"test,omitempty"`
+	Interval      string            `yaml:"interval,omitempty"`
+	Timeout       string            `yaml:"timeout,omitempty"`
+	StartPeriod   string            `yaml:"start_period,omitempty"`
+	StartInterval string            `yaml:"start_interval,omitempty"`
+	Retries       int               `yaml:"retries,omitempty"`
+	Disable       bool              `yaml:"disable,omitempty"`
+}
+
+type ComposeLogging struct {
+	Driver  string            `yaml:"driver,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+}
+
+// ComposePort 端口，支持短格式 "8080:80/udp" 和长格式
+type ComposePort struct {
+	Target    int    `yaml:"target"`
+	Published int    `yaml:"published,omitempty"`
+	Protocol  string `yaml:"protocol,omitempty"` // tcp udp
+	Mode      string `yaml:"mode,omitempty"`     // ingress host
+}
+
+// ComposeServiceVolume 服务挂载，支持短格式 "data:/data:ro" 和长格式
+type ComposeServiceVolume struct {
+	Type     string `yaml:"type,omitempty"` // volume bind tmpfs
+	Source   string `yaml:"source,omitempty"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only,omitempty"`
+	Bind     *struct {
+		Propagation string `yaml:"propagation,omitempty"`
+	} `yaml:"bind,omitempty"`
+	Volume *struct {
+		NoCopy bool `yaml:"nocopy,omitempty"`
+	} `yaml:"volume,omitempty"`
+	Tmpfs *struct {
+		Size string `yaml:"size,omitempty"` // 64m
+	} `yaml:"tmpfs,omitempty"`
+}
+
+// ComposeServiceNetwork 服务加入的网络
+type ComposeServiceNetwork struct {
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// ComposeServiceFile 服务引用的 configs、secrets，支持短格式 "app_config" 和长格式
+type ComposeServiceFile struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target,omitempty"`
+	UID    string `yaml:"uid,omitempty"`
+	GID    string `yaml:"gid,omitempty"`
+	Mode   *int   `yaml:"mode,omitempty"`
+}
+
+// ComposeNetwork 顶层 networks 节点
+type ComposeNetwork struct {
+	Name       string            `yaml:"name,omitempty"`
+	Driver     string            `yaml:"driver,omitempty"` // 默认 overlay
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+	Attachable bool              `yaml:"attachable,omitempty"`
+	Internal   bool              `yaml:"internal,omitempty"`
+	Labels     ComposeMapping    `yaml:"labels,omitempty"`
+	External   ComposeExternal   `yaml:"external,omitempty"`
+}
+
+// ComposeVolume 顶层 volumes 节点
+type ComposeVolume struct {
+	Name       string            `yaml:"name,omitempty"`
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+	Labels     ComposeMapping    `yaml:"labels,omitempty"`
+	External   ComposeExternal   `yaml:"external,omitempty"`
+}
+
+// ComposeFileObject 顶层 configs、secrets 节点
+type ComposeFileObject struct {
+	Name           string          `yaml:"name,omitempty"`
+	File           string          `yaml:"file,omitempty"`        // 从文件读取
+	Content        string          `yaml:"content,omitempty"`     // 直接写内容
+	Environment    string          `yaml:"environment,omitempty"` // 从环境变量读取
+	Labels         ComposeMapping  `yaml:"labels,omitempty"`
+	TemplateDriver string          `yaml:"template_driver,omitempty"`
+	External       ComposeExternal `yaml:"external,omitempty"`
+}
+
+// ComposeExternal external: true 或旧格式 external: {name: xxx}
+type ComposeExternal struct {
+	External bool
+	Name     string
+}
+
+func (receiver *ComposeExternal) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var legacy struct {
+			Name string `yaml:"name"`
+		}
+		if err := value.Decode(&legacy); err != nil {
+			return err
+		}
+		receiver.External, receiver.Name = true, legacy.Name
+		return nil
+	}
+	return value.Decode(&receiver.External)
+}
+
+func (receiver ComposeExternal) MarshalYAML() (any, error) {
+	if receiver.Name != "" {
+		return map[string]string{"name": receiver.Name}, nil
+	}
+	return receiver.External, nil
+}
+
+func (receiver ComposeExternal) IsZero() bool {
+	return !receiver.External
+}
+
+// ComposeMapping 支持 map 和 ["KEY=VALUE"] 两种写法
+type ComposeMapping map[string]string
+
+func (receiver *ComposeMapping) UnmarshalYAML(value *yaml.Node) error {
+	result := ComposeMapping{}
+	switch value.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			item := value.Content[i+1]
+			if item.ShortTag() == "!!null" {
+				result[value.Content[i].Value] = ""
+				continue
+			}
+			result[value.Content[i].Value] = item.Value
+		}
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			key, val, _ := strings.Cut(item.Value, "=")
+			result[key] = val
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or a list", value.Line)
+	}
+	*receiver = result
+	return nil
+}
+
+// ComposeStringList 支持单个字符串和列表两种写法
+type ComposeStringList []string
+
+func (receiver *ComposeStringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*receiver = ComposeStringList{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(receiver))
+}
+
+// ComposeCommand 字符串按 shell 规则拆分，列表原样使用
+type ComposeCommand []string
+
+func (receiver *ComposeCommand) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*receiver = ParseShellArgs(value.Value)
+		return nil
+	}
+	return value.Decode((*[]string)(receiver))
+}
+
+// ComposeHealthTest 字符串等价于 ["CMD-SHELL", "..."]
+type ComposeHealthTest []string
+
+func (receiver *ComposeHealthTest) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*receiver = ComposeHealthTest{"CMD-SHELL", value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(receiver))
+}
+
+// ComposeUlimit 支持 nofile: 65535 和 nofile: {soft: 1024, hard: 65535}
+type ComposeUlimit struct {
+	Soft int64 `yaml:"soft"`
+	Hard int64 `yaml:"hard"`
+}
+
+func (receiver *ComposeUlimit) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if err := value.Decode(&receiver.Soft); err != nil {
+			return err
+		}
+		receiver.Hard = receiver.Soft
+		return nil
+	}
+	type plain ComposeUlimit
+	return value.Decode((*plain)(receiver))
+}
+
+// ComposeServiceNetworks 支持 ["front", "back"] 和 {front: {aliases: [...]}} 两种写法
+type ComposeServiceNetworks map[string]ComposeServiceNetwork
+
+func (receiver *ComposeServiceNetworks) UnmarshalYAML(value *yaml.Node) error {
+	result := ComposeServiceNetworks{}
+	switch value.Kind {
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			result[item.Value] = ComposeServiceNetwork{}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			var network ComposeServiceNetwork
+			if err := value.Content[i+1].Decode(&network); err != nil {
+				return err
+			}
+			result[value.Content[i].Value] = network
+		}
+	default:
+		return fmt.Errorf("line %d: networks must be a mapping or a list", value.Line)
+	}
+	*receiver = result
+	return nil
+}
+
+func (receiver *ComposeServiceFile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		receiver.Source = value.Value
+		return nil
+	}
+	type plain ComposeServiceFile
+	return value.Decode((*plain)(receiver))
+}
+
+func (receiver *ComposeServiceVolume) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		type plain ComposeServiceVolume
+		return value.Decode((*plain)(receiver))
+	}
+
+	// source:target[:mode]、target
+	parts := strings.Split(value.Value, ":")
+	switch len(parts) {
+	case 1:
+		receiver.Target = parts[0]
+	case 2, 3:
+		receiver.Source, receiver.Target = parts[0], parts[1]
+		if len(parts) == 3 {
+			for _, option := range strings.Split(parts[2], ",") {
+				switch option {
+				case "ro":
+					receiver.ReadOnly = true
+				case "rw":
+				case "nocopy":
+					receiver.Volume = &struct {
+						NoCopy bool `yaml:"nocopy,omitempty"`
+					}{NoCopy: true}
+				default:
+					return fmt.Errorf("line %d: invalid volume mode %q", value.Line, option)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("line %d: invalid volume %q", value.Line, value.Value)
+	}
+
+	receiver.Type = "volume"
+	if isComposeBindSource(receiver.Source) {
+		receiver.Type = "bind"
+	}
+	return nil
+}
+
+// ComposePorts 端口列表，短格式的端口范围会展开为多个端口
+type ComposePorts []ComposePort
+
+func (receiver *ComposePorts) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: ports must be a list", value.Line)
+	}
+	var result ComposePorts
+	for _, item := range value.Content {
+		if item.Kind != yaml.ScalarNode {
+			var port ComposePort
+			if err := item.Decode(&port); err != nil {
+				return err
+			}
+			result = append(result, port)
+			continue
+		}
+		ports, err := parseComposePort(item.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", item.Line, err)
+		}
+		result = append(result, ports...)
+	}
+	*receiver = result
+	return nil
+}
+
+// parseComposePort 解析 [ip:][published[-end]:]target[-end][/protocol]
+func parseComposePort(value string) ([]ComposePort, error) {
+	spec, protocol, _ := strings.Cut(value, "/")
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid port %q", value)
+	}
+
+	targetStart, targetEnd, err := parseComposePortRange(parts[len(parts)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", value)
+	}
+	publishedStart, publishedEnd := 0, 0
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		if publishedStart, publishedEnd, err = parseComposePortRange(parts[len(parts)-2]); err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		if publishedEnd-publishedStart != targetEnd-targetStart {
+			return nil, fmt.Errorf("invalid port %q: published and target ranges differ", value)
+		}
+	}
+
+	var ports []ComposePort
+	for offset := 0; offset <= targetEnd-targetStart; offset++ {
+		port := ComposePort{Target: targetStart + offset, Protocol: protocol}
+		if publishedStart > 0 {
+			port.Published = publishedStart + offset
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func parseComposePortRange(value string) (int, int, error) {
+	startValue, endValue, isRange := strings.Cut(value, "-")
+	start, err := strconv.Atoi(startValue)
+	if err != nil || start <= 0 {
+		return 0, 0, fmt.Errorf("invalid port %q", value)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(endValue)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid port range %q", value)
+	}
+	return start, end, nil
+}
+
+func isComposeBindSource(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~")
+}
+
+// ComposeLoadOptions 解析 compose 文件的参数
+type ComposeLoadOptions struct {
+	WorkingDir string            // 相对路径的基准目录，默认当前目录
+	Env        map[string]string // 变量插值 ${VAR} 使用的环境变量，为 nil 时读取当前进程的环境变量
+}
+
+// ParseComposeFile 读取并解析 compose 文件，相对路径以文件所在目录为准
+func ParseComposeFile(path string, env map[string]string) (ComposeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ComposeFile{}, err
+	}
+	workingDir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ComposeFile{}, err
+	}
+	return ParseCompose(data, ComposeLoadOptions{WorkingDir: workingDir, Env: env})
+}
+
+// ParseCompose 解析 compose 内容：变量插值 -> 解析 -> 校验
+func ParseCompose(data []byte, options ComposeLoadOptions) (ComposeFile, error) {
+	lookup := ComposeFile{Env: options.Env}.lookupEnv
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return ComposeFile{}, fmt.Errorf("parse compose file failed: %w", err)
+	}
+	if err := interpolateComposeNode(&root, lookup); err != nil {
+		return ComposeFile{}, err
+	}
+	resolveComposeEnvironment(&root, lookup)
+
+	var file ComposeFile
+	if err := root.Decode(&file); err != nil {
+		return ComposeFile{}, fmt.Errorf("parse compose file failed: %w", err)
+	}
+
+	file.WorkingDir, file.Env = options.WorkingDir, options.Env
+	if file.WorkingDir == "" {
+		file.WorkingDir, _ = os.Getwd()
+	}
+
+	if len(file.Services) == 0 {
+		return file, fmt.Errorf("compose file has no services")
+	}
+	for name, svc := range file.Services {
+		if svc.Image == "" {
+			return file, fmt.Errorf("service %s: image is required", name)
+		}
+	}
+	return file, nil
+}
+
+// lookupEnv 读取环境变量：设置了 Env 时只读取 Env，否则读取当前进程的环境变量
+func (receiver ComposeFile) lookupEnv(key string) (string, bool) {
+	if receiver.Env == nil {
+		return os.LookupEnv(key)
+	}
+	value, exists := receiver.Env[key]
+	return value, exists
+}
+
+// resolveComposeEnvironment 服务 environment 中只有 key 的项（- TOKEN、TOKEN:）从环境变量读取，没有设置时忽略
+func resolveComposeEnvironment(root *yaml.Node, lookup func(string) (string, bool)) {
+	services := composeMappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(services.Content); i += 2 {
+		environment := composeMappingValue(services.Content[i], "environment")
+		if environment == nil {
+			continue
+		}
+		var content []*yaml.Node
+		switch environment.Kind {
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(environment.Content); j += 2 {
+				key, item := environment.Content[j], environment.Content[j+1]
+				if item.ShortTag() == "!!null" {
+					value, exists := lookup(key.Value)
+					if !exists {
+						continue
+					}
+					item = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+				}
+				content = append(content, key, item)
+			}
+		case yaml.SequenceNode:
+			for _, item := range environment.Content {
+				if item.Kind == yaml.ScalarNode && !strings.Contains(item.Value, "=") {
+					value, exists := lookup(item.Value)
+					if !exists {
+						continue
+					}
+					item = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item.Value + "=" + value}
+				}
+				content = append(content, item)
+			}
+		default:
+			continue
+		}
+		environment.Content = content
+	}
+}
+
+// composeMappingValue mapping（或 document）中 key 对应的值
+func composeMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// interpolateComposeNode 替换所有值中的变量（key 不替换）
+func interpolateComposeNode(node *yaml.Node, lookup func(string) (string, bool)) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := interpolateComposeNode(item, lookup); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := interpolateComposeNode(node.Content[i], lookup); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		value, err := interpolateComposeValue(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		// 未加引号的值重新推断类型，如 ports: ${PORT} 替换后为数字
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
+
+// interpolateComposeValue 支持 $VAR、${VAR}、${VAR:-default}、${VAR-default}、${VAR:?err}、${VAR?err}、${VAR:+alt}、${VAR+alt}，$$ 转义为 $
+func interpolateComposeValue(value string, lookup func(string) (string, bool)) (string, error) {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 >= len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		switch next := value[i+1]; {
+		case next == '$':
+			builder.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchComposeBrace(value, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation format for %q", value)
+			}
+			resolved, err := resolveComposeVariable(value[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(resolved)
+			i = end
+		case isComposeVariableChar(next, true):
+			end := i + 1
+			for end < len(value) && isComposeVariableChar(value[end], false) {
+				end++
+			}
+			resolved, _ := lookup(value[i+1 : end])
+			builder.WriteString(resolved)
+			i = end - 1
+		default:
+			builder.WriteByte('$')
+		}
+	}
+	return builder.String(), nil
+}
+
+// matchComposeBrace 找到与 open 位置的 { 对应的 }，支持嵌套 ${A:-${B}}
+func matchComposeBrace(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func resolveComposeVariable(expression string, lookup func(string) (string, bool)) (string, error) {
+	nameEnd := 0
+	for nameEnd < len(expression) && isComposeVariableChar(expression[nameEnd], nameEnd == 0) {
+		nameEnd++
+	}
+	name, modifier := expression[:nameEnd], expression[nameEnd:]
+	if name == "" {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", expression)
+	}
+
+	value, exists := lookup(name)
+	operator := ""
+	for _, candidate := range []string{":-", ":?", ":+", "-", "?", "+"} {
+		if strings.HasPrefix(modifier, candidate) {
+			operator = candidate
+			break
+		}
+	}
+	if operator == "" && modifier != "" {
+		return "", fmt.Errorf("invalid interpolation format for ${%s}", expression)
+	}
+	argument := strings.TrimPrefix(modifier, operator)
+
+	// 带 : 的写法把空值视为未设置
+	unset := !exists || (strings.HasPrefix(operator, ":") && value == "")
+	switch strings.TrimPrefix(operator, ":") {
+	case "-":
+		if unset {
+			return interpolateComposeValue(argument, lookup)
+		}
+	case "?":
+		if unset {
+			return "", fmt.Errorf("required variable %s is missing a value: %s", name, argument)
+		}
+	case "+":
+		if unset {
+			return "", nil
+		}
+		return interpolateComposeValue(argument, lookup)
+	}
+	return value, nil
+}
+
+func isComposeVariableChar(char byte, first bool) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (!first && char >= '0' && char <= '9')
+}
+
+// ServiceSpecs 将 compose 中的服务转换为 ServiceSpec（按名称排序）
+// 服务名为 {namespace}_{service}，引用的配置、密钥只填充名称，ID 在部署时查询
+func (receiver ComposeFile) ServiceSpecs(namespace string) ([]ServiceSpec, error) {
+	names := make([]string, 0, len(receiver.Services))
+	for name := range receiver.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	specs := make([]ServiceSpec, 0, len(names))
+	for _, name := range names {
+		spec, err := receiver.ServiceSpec(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// ServiceSpec 将 compose 中的单个服务转换为 ServiceSpec
+func (receiver ComposeFile) ServiceSpec(namespace, serviceName string) (ServiceSpec, error) {
+	svc, exists := receiver.Services[serviceName]
+	if !exists {
+		return ServiceSpec{}, fmt.Errorf("service %s not found in compose file", serviceName)
+	}
+	wrap := func(err error) error { return fmt.Errorf("service %s: %w", serviceName, err) }
+
+	builder := NewServiceSpec(stackResourceName(namespace, serviceName), svc.Image).
+		Label(StackNamespaceLabel, namespace).
+		Label(stackImageLabel, svc.Image).
+		ContainerLabel(StackNamespaceLabel, namespace).
+		Command(svc.Entrypoint...).
+		Args(svc.Command...)
+	for key, value := range svc.Deploy.Labels {
+		builder.Label(key, value)
+	}
+	for key, value := range svc.Labels {
+		builder.ContainerLabel(key, value)
+	}
+	for _, port := range svc.Ports {
+		builder.PublishPort(port.Published, port.Target, port.Protocol, port.Mode)
+	}
+	for _, constraint := range svc.Deploy.Placement.Constraints {
+		builder.Constraint(constraint)
+	}
+	for _, preference := range svc.Deploy.Placement.Preferences {
+		builder.Spread(preference.Spread)
+	}
+	builder.MaxReplicasPerNode(svc.Deploy.Placement.MaxReplicas)
+
+	// 资源
+	limits, reservations := svc.Deploy.Resources.Limits, svc.Deploy.Resources.Reservations
+	builder.LimitMemory(limits.Memory).ReserveMemory(reservations.Memory)
+	for _, item := range []struct {
+		value string
+		apply func(float64) *ServiceSpecBuilder
+	}{{limits.CPUs, builder.LimitCPU}, {reservations.CPUs, builder.ReserveCPU}} {
+		if item.value == "" {
+			continue
+		}
+		cpus, err := strconv.ParseFloat(item.value, 64)
+		if err != nil {
+			return ServiceSpec{}, wrap(fmt.Errorf("invalid cpus %q", item.value))
+		}
+		item.apply(cpus)
+	}
+
+	// 副本模式
+	replicas := 1
+	if svc.Deploy.Replicas != nil {
+		replicas = *svc.Deploy.Replicas
+	}
+	switch svc.Deploy.Mode {
+	case "", "replicated":
+		builder.Replicas(replicas)
+	case "global":
+		builder.Global()
+	case "replicated-job":
+		maxConcurrent := replicas
+		if svc.Deploy.MaxConcurrent != nil {
+			maxConcurrent = *svc.Deploy.MaxConcurrent
+		}
+		builder.Spec(func(spec *ServiceSpec) {
+			spec.Mode = ServiceMode{ReplicatedJob: &ReplicatedJob{MaxConcurrent: maxConcurrent, TotalCompletions: replicas}}
+		})
+	case "global-job":
+		builder.Spec(func(spec *ServiceSpec) { spec.Mode = ServiceMode{GlobalJob: &struct{}{}} })
+	default:
+		return ServiceSpec{}, wrap(fmt.Errorf("invalid deploy mode %q", svc.Deploy.Mode))
+	}
+
+	spec, err := builder.Build()
+	if err != nil {
+		return spec, wrap(err)
+	}
+
+	containerSpec := &spec.TaskTemplate.ContainerSpec
+	containerSpec.Hostname, containerSpec.User, containerSpec.Dir = svc.Hostname, svc.User, svc.WorkingDir
+	containerSpec.Init, containerSpec.ReadOnly, containerSpec.TTY, containerSpec.OpenStdin = svc.Init, svc.ReadOnly, svc.TTY, svc.StdinOpen
+	containerSpec.StopSignal = svc.StopSignal
+	containerSpec.CapabilityAdd, containerSpec.CapabilityDrop = svc.CapAdd, svc.CapDrop
+	containerSpec.DNSConfig = DNSConfig{Nameservers: svc.DNS, Search: svc.DNSSearch}
+	spec.TaskTemplate.Resources.Limits.Pids = svc.Deploy.Resources.Limits.Pids
+	if len(svc.Sysctls) > 0 {
+		containerSpec.Sysctls = svc.Sysctls
+	}
+	for _, host := range svc.ExtraHosts {
+		// host:ip -> ip host
+		name, ip, found := strings.Cut(host, ":")
+		if !found {
+			name, ip, _ = strings.Cut(host, "=")
+		}
+		containerSpec.Hosts = append(containerSpec.Hosts, ip+" "+name)
+	}
+	for _, name := range sortedKeys(svc.Ulimits) {
+		containerSpec.Ulimits = append(containerSpec.Ulimits, Ulimit{Name: name, Soft: svc.Ulimits[name].Soft, Hard: svc.Ulimits[name].Hard})
+	}
+	if svc.Logging != nil {
+		spec.TaskTemplate.LogDriver = &Driver{Name: svc.Logging.Driver, Options: svc.Logging.Options}
+	}
+	if svc.Deploy.EndpointMode != "" {
+		if spec.EndpointSpec == nil {
+			spec.EndpointSpec = &EndpointSpec{}
+		}
+		spec.EndpointSpec.Mode = svc.Deploy.EndpointMode
+	}
+
+	if containerSpec.Env, err = receiver.serviceEnv(svc); err != nil {
+		return spec, wrap(err)
+	}
+	if containerSpec.Mounts, err = receiver.serviceMounts(namespace, svc); err != nil {
+		return spec, wrap(err)
+	}
+	if spec.TaskTemplate.Networks, err = receiver.serviceNetworks(namespace, serviceName, svc); err != nil {
+		return spec, wrap(err)
+	}
+	if containerSpec.Configs, containerSpec.Secrets, err = receiver.serviceFiles(namespace, svc); err != nil {
+		return spec, wrap(err)
+	}
+	if err = applyComposeDurations(&spec, svc); err != nil {
+		return spec, wrap(err)
+	}
+	return spec, nil
+}
+
+// serviceEnv env_file 在前，environment 覆盖，按 KEY 排序保证每次部署生成的配置一致
+func (receiver ComposeFile) serviceEnv(svc ComposeService) ([]string, error) {
+	env := map[string]string{}
+	for _, path := range svc.EnvFile {
+		values, err := readComposeEnvFile(receiver.path(path))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			env[key] = value
+		}
+	}
+	for key, value := range svc.Environment {
+		env[key] = value
+	}
+
+	var result []string
+	for _, key := range sortedKeys(env) {
+		result = append(result, key+"="+env[key])
+	}
+	return result, nil
+}
+
+func readComposeEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		result[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return result, nil
+}
+
+func (receiver ComposeFile) serviceMounts(namespace string, svc ComposeService) ([]Mount, error) {
+	var mounts []Mount
+	for _, volume := range svc.Volumes {
+		mount := Mount{Type: volume.Type, Source: volume.Source, Target: volume.Target, ReadOnly: volume.ReadOnly}
+		if mount.Type == "" {
+			mount.Type = "volume"
+		}
+
+		switch mount.Type {
+		case "bind":
+			mount.Source = receiver.path(volume.Source)
+			if volume.Bind != nil && volume.Bind.Propagation != "" {
+				mount.BindOptions = &BindOptions{Propagation: volume.Bind.Propagation}
+			}
+		case "volume":
+			options := &VolumeOptions{Labels: map[string]string{StackNamespaceLabel: namespace}}
+			if volume.Volume != nil {
+				options.NoCopy = volume.Volume.NoCopy
+			}
+			if volume.Source != "" {
+				declared, exists := receiver.Volumes[volume.Source]
+				if !exists {
+					return nil, fmt.Errorf("undefined volume %q", volume.Source)
+				}
+				mount.Source = stackObjectName(namespace, volume.Source, declared.Name, declared.External)
+				for key, value := range declared.Labels {
+					options.Labels[key] = value
+				}
+				if declared.Driver != "" && !declared.External.External {
+					options.DriverConfig = &Driver{Name: declared.Driver, Options: declared.DriverOpts}
+				}
+			}
+			mount.VolumeOptions = options
+		case "tmpfs":
+			if volume.Tmpfs != nil && volume.Tmpfs.Size != "" {
+				size, err := ParseMemoryBytes(volume.Tmpfs.Size)
+				if err != nil {
+					return nil, err
+				}
+				mount.TmpfsOptions = &TmpfsOptions{SizeBytes: size}
+			}
+		default:
+			return nil, fmt.Errorf("invalid volume type %q", mount.Type)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// serviceNetworks 未指定网络时加入 default 网络，服务名作为网络内的别名
+func (receiver ComposeFile) serviceNetworks(namespace, serviceName string, svc ComposeService) ([]NetworkAttachmentConfig, error) {
+	networks := svc.Networks
+	if len(networks) == 0 {
+		networks = ComposeServiceNetworks{"default": {}}
+	}
+
+	var result []NetworkAttachmentConfig
+	for _, name := range sortedKeys(networks) {
+		declared, exists := receiver.Networks[name]
+		if !exists && name != "default" {
+			return nil, fmt.Errorf("undefined network %q", name)
+		}
+		result = append(result, NetworkAttachmentConfig{
+			Target:  stackObjectName(namespace, name, declared.Name, declared.External),
+			Aliases: append([]string{serviceName}, networks[name].Aliases...),
+		})
+	}
+	return result, nil
+}
+
+func (receiver ComposeFile) serviceFiles(namespace string, svc ComposeService) ([]ServiceConfigJson, []ServiceSecretJson, error) {
+	var configs []ServiceConfigJson
+	for _, item := range svc.Configs {
+		declared, exists := receiver.Configs[item.Source]
+		if !exists {
+			return nil, nil, fmt.Errorf("undefined config %q", item.Source)
+		}
+		configs = append(configs, ServiceConfigJson{
+			ConfigName: stackObjectName(namespace, item.Source, declared.Name, declared.External),
+			File:       composeFileTarget(item, "/"+item.Source),
+		})
+	}
+
+	var secrets []ServiceSecretJson
+	for _, item := range svc.Secrets {
+		declared, exists := receiver.Secrets[item.Source]
+		if !exists {
+			return nil, nil, fmt.Errorf("undefined secret %q", item.Source)
+		}
+		secrets = append(secrets, ServiceSecretJson{
+			SecretName: stackObjectName(namespace, item.Source, declared.Name, declared.External),
+			File:       composeFileTarget(item, item.Source),
+		})
+	}
+	return configs, secrets, nil
+}
+
+func composeFileTarget(item ComposeServiceFile, defaultTarget string) ServiceConfigFileJson {
+	file := ServiceConfigFileJson{Name: item.Target, UID: item.UID, GID: item.GID, Mode: 0444}
+	if file.Name == "" {
+		file.Name = defaultTarget
+	}
+	if file.UID == "" {
+		file.UID = "0"
+	}
+	if file.GID == "" {
+		file.GID = "0"
+	}
+	if item.Mode != nil {
+		file.Mode = *item.Mode
+	}
+	return file
+}
+
+// applyComposeDurations 转换 deploy、healthcheck 中的时间（10s、1m30s）
+func applyComposeDurations(spec *ServiceSpec, svc ComposeService) error {
+	var err error
+	duration := func(value string) int64 {
+		if value == "" || err != nil {
+			return 0
+		}
+		var parsed time.Duration
+		if parsed, err = time.ParseDuration(value); err != nil {
+			err = fmt.Errorf("invalid duration %q", value)
+		}
+		return int64(parsed)
+	}
+
+	spec.TaskTemplate.ContainerSpec.StopGracePeriod = duration(svc.StopGracePeriod)
+	if policy := svc.Deploy.RestartPolicy; policy != nil {
+		spec.TaskTemplate.RestartPolicy = &RestartPolicy{Condition: policy.Condition, Delay: duration(policy.Delay), MaxAttempts: policy.MaxAttempts, Window: duration(policy.Window)}
+	}
+	updateConfig := func(config *ComposeUpdateConfig) *UpdateConfig {
+		if config == nil {
+			return nil
+		}
+		result := &UpdateConfig{Parallelism: 1, Delay: duration(config.Delay), FailureAction: config.FailureAction, Monitor: duration(config.Monitor), MaxFailureRatio: config.MaxFailureRatio, Order: config.Order}
+		if config.Parallelism != nil {
+			result.Parallelism = *config.Parallelism
+		}
+		return result
+	}
+	// 与 docker stack deploy 一致：没有 update_config 时不提交，使用 daemon 的默认值（stop-first）
+	spec.UpdateConfig = updateConfig(svc.Deploy.UpdateConfig)
+	spec.RollbackConfig = updateConfig(svc.Deploy.RollbackConfig)
+
+	if health := svc.Healthcheck; health != nil {
+		test := []string(health.Test)
+		if health.Disable {
+			test = []string{"NONE"}
+		}
+		spec.TaskTemplate.ContainerSpec.Healthcheck = &HealthConfig{
+			Test:          test,
+			Interval:      duration(health.Interval),
+			Timeout:       duration(health.Timeout),
+			StartPeriod:   duration(health.StartPeriod),
+			StartInterval: duration(health.StartInterval),
+			Retries:       health.Retries,
+		}
+	}
+	return err
+}
+
+// path 相对路径以 compose 文件所在目录为准
+func (receiver ComposeFile) path(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(receiver.WorkingDir, path)
+}
+
+// stackResourceName stack 内的资源名称 {namespace}_{name}
+func stackResourceName(namespace, name string) string {
+	return namespace + "_" + name
+}
+
+// stackObjectName 网络、卷、配置、密钥的实际名称：external 时使用原名，指定了 name 时使用 name，否则 {namespace}_{key}
+func stackObjectName(namespace, key, name string, external ComposeExternal) string {
+	switch {
+	case external.External && external.Name != "":
+		return external.Name
+	case name != "":
+		return name
+	case external.External:
+		return key
+	default:
+		return stackResourceName(namespace, key)
+	}
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
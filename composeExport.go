@@ -0,0 +1,389 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeExportOptions 导出 compose 文件的参数
+type ComposeExportOptions struct {
+	Namespace string // 只导出该 stack 的服务（com.docker.stack.namespace），名称去掉 {namespace}_ 前缀
+	Prefix    string // 只导出名称以 Prefix 开头的服务
+}
+
+// networkInspectJson 网络详情 GET /networks
+type networkInspectJson struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Attachable bool              `json:"Attachable"`
+	Internal   bool              `json:"Internal"`
+	Labels     map[string]string `json:"Labels"`
+	Options    map[string]string `json:"Options"`
+}
+
+// Export 将集群中的服务导出为 compose 文件
+// 不属于该 stack 的网络、所有配置和密钥导出为 external（它们已存在于集群中）
+func (receiver stack) Export(ctx context.Context, options ComposeExportOptions) (ComposeFile, error) {
+	servicesUrl := receiver.api.URL("/services")
+	if options.Namespace != "" {
+		servicesUrl += "?filters=" + stackFilter(options.Namespace)
+	}
+	services, err := UnixRequestDecode[[]ServiceInspectJson](ctx, receiver.api.httpClient, http.MethodGet, servicesUrl, nil, nil)
+	if err != nil {
+		return ComposeFile{}, err
+	}
+
+	networks, err := UnixRequestDecode[[]networkInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/networks"), nil, nil)
+	if err != nil {
+		return ComposeFile{}, err
+	}
+
+	exporter := newComposeExporter(options.Namespace, networks)
+	for _, svc := range services {
+		if strings.HasPrefix(svc.Spec.Name, options.Prefix) {
+			exporter.addService(svc.Spec)
+		}
+	}
+	if len(exporter.file.Services) == 0 {
+		return exporter.file, fmt.Errorf("no services found to export")
+	}
+	return exporter.file, nil
+}
+
+// YAML 输出为 compose v3 文件内容，值中的 $ 转义为 $$，重新解析时不会被当作变量
+func (receiver ComposeFile) YAML() ([]byte, error) {
+	if receiver.Version == "" {
+		receiver.Version = "3.8"
+	}
+	var root yaml.Node
+	if err := root.Encode(receiver); err != nil {
+		return nil, err
+	}
+	escapeComposeNode(&root)
+	return yaml.Marshal(&root)
+}
+
+// escapeComposeNode 将所有值（不包括 key）中的 $ 转义为 $$，与 interpolateComposeNode 对应
+func escapeComposeNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, item := range node.Content {
+			escapeComposeNode(item)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			escapeComposeNode(node.Content[i])
+		}
+	case yaml.ScalarNode:
+		node.Value = strings.ReplaceAll(node.Value, "$", "$$")
+	}
+}
+
+// composeExporter 将 ServiceSpec 转换为 compose 服务，同时收集顶层的 networks、volumes、configs、secrets
+type composeExporter struct {
+	namespace string
+	networks  map[string]networkInspectJson // 网络ID、名称 -> 网络
+	file      ComposeFile
+}
+
+func newComposeExporter(namespace string, networks []networkInspectJson) *composeExporter {
+	exporter := &composeExporter{
+		namespace: namespace,
+		networks:  map[string]networkInspectJson{},
+		file: ComposeFile{
+			Services: map[string]ComposeService{},
+			Networks: map[string]ComposeNetwork{},
+			Volumes:  map[string]ComposeVolume{},
+			Configs:  map[string]ComposeFileObject{},
+			Secrets:  map[string]ComposeFileObject{},
+		},
+	}
+	for _, network := range networks {
+		exporter.networks[network.ID] = network
+		exporter.networks[network.Name] = network
+	}
+	return exporter
+}
+
+// key 资源在 compose 中的 key，以及部署时是否需要显式指定 name
+// 导出 stack 时，{namespace}_xxx 的资源 key 为 xxx，部署到同名 stack 时会得到相同的名称
+func (receiver *composeExporter) key(name string) (string, bool) {
+	if receiver.namespace != "" && strings.HasPrefix(name, receiver.namespace+"_") {
+		return strings.TrimPrefix(name, receiver.namespace+"_"), false
+	}
+	return name, true
+}
+
+func (receiver *composeExporter) addService(spec ServiceSpec) {
+	serviceKey, _ := receiver.key(spec.Name)
+	containerSpec := spec.TaskTemplate.ContainerSpec
+
+	image := spec.Labels[stackImageLabel]
+	if image == "" {
+		image = TrimImageDigest(containerSpec.Image)
+	}
+
+	svc := ComposeService{
+		Image:           image,
+		Entrypoint:      containerSpec.Command,
+		Command:         containerSpec.Args,
+		Environment:     envToMap(containerSpec.Env),
+		Labels:          withoutStackLabels(containerSpec.Labels),
+		Hostname:        containerSpec.Hostname,
+		User:            containerSpec.User,
+		WorkingDir:      containerSpec.Dir,
+		StopGracePeriod: formatComposeDuration(containerSpec.StopGracePeriod),
+		StopSignal:      containerSpec.StopSignal,
+		DNS:             containerSpec.DNSConfig.Nameservers,
+		DNSSearch:       containerSpec.DNSConfig.Search,
+		Init:            containerSpec.Init,
+		ReadOnly:        containerSpec.ReadOnly,
+		TTY:             containerSpec.TTY,
+		StdinOpen:       containerSpec.OpenStdin,
+		CapAdd:          containerSpec.CapabilityAdd,
+		CapDrop:         containerSpec.CapabilityDrop,
+		Sysctls:         containerSpec.Sysctls,
+		Deploy:          receiver.deploy(spec),
+	}
+	if len(svc.Environment) == 0 {
+		svc.Environment = nil
+	}
+	for _, host := range containerSpec.Hosts {
+		// ip hostname -> hostname:ip
+		if fields := strings.Fields(host); len(fields) >= 2 {
+			svc.ExtraHosts = append(svc.ExtraHosts, fields[1]+":"+fields[0])
+		}
+	}
+	for _, ulimit := range containerSpec.Ulimits {
+		if svc.Ulimits == nil {
+			svc.Ulimits = map[string]ComposeUlimit{}
+		}
+		svc.Ulimits[ulimit.Name] = ComposeUlimit{Soft: ulimit.Soft, Hard: ulimit.Hard}
+	}
+	if spec.EndpointSpec != nil {
+		for _, port := range spec.EndpointSpec.Ports {
+			svc.Ports = append(svc.Ports, ComposePort{Target: port.TargetPort, Published: port.PublishedPort, Protocol: port.Protocol, Mode: port.PublishMode})
+		}
+	}
+	if health := containerSpec.Healthcheck; health != nil {
+		svc.Healthcheck = &ComposeHealthcheck{
+			Test:          health.Test,
+			Interval:      formatComposeDuration(health.Interval),
+			Timeout:       formatComposeDuration(health.Timeout),
+			StartPeriod:   formatComposeDuration(health.StartPeriod),
+			StartInterval: formatComposeDuration(health.StartInterval),
+			Retries:       health.Retries,
+		}
+		if len(health.Test) == 1 && health.Test[0] == "NONE" {
+			svc.Healthcheck = &ComposeHealthcheck{Disable: true}
+		}
+	}
+	if logDriver := spec.TaskTemplate.LogDriver; logDriver != nil {
+		svc.Logging = &ComposeLogging{Driver: logDriver.Name, Options: logDriver.Options}
+	}
+
+	svc.Volumes = receiver.volumes(containerSpec.Mounts)
+	svc.Networks = receiver.serviceNetworks(serviceKey, spec.TaskTemplate.Networks)
+	for _, config := range containerSpec.Configs {
+		svc.Configs = append(svc.Configs, receiver.fileObject(receiver.file.Configs, config.ConfigName, config.File))
+	}
+	for _, secret := range containerSpec.Secrets {
+		svc.Secrets = append(svc.Secrets, receiver.fileObject(receiver.file.Secrets, secret.SecretName, secret.File))
+	}
+	receiver.file.Services[serviceKey] = svc
+}
+
+func (receiver *composeExporter) deploy(spec ServiceSpec) ComposeDeploy {
+	placement := spec.TaskTemplate.Placement
+	resources := spec.TaskTemplate.Resources
+	deploy := ComposeDeploy{
+		Labels: withoutStackLabels(spec.Labels),
+		Placement: ComposePlacement{
+			Constraints: placement.Constraints,
+			MaxReplicas: placement.MaxReplicas,
+		},
+		Resources: ComposeResources{
+			Limits:       ComposeResource{CPUs: formatComposeCPUs(resources.Limits.NanoCPUs), Memory: formatMemoryBytes(resources.Limits.MemoryBytes), Pids: resources.Limits.Pids},
+			Reservations: ComposeResource{CPUs: formatComposeCPUs(resources.Reservations.NanoCPUs), Memory: formatMemoryBytes(resources.Reservations.MemoryBytes)},
+		},
+		UpdateConfig:   formatComposeUpdateConfig(spec.UpdateConfig),
+		RollbackConfig: formatComposeUpdateConfig(spec.RollbackConfig),
+	}
+	for _, preference := range placement.Preferences {
+		deploy.Placement.Preferences = append(deploy.Placement.Preferences, ComposePlacementPreference{Spread: preference.Spread.SpreadDescriptor})
+	}
+	if spec.EndpointSpec != nil && spec.EndpointSpec.Mode != "" && spec.EndpointSpec.Mode != "vip" {
+		deploy.EndpointMode = spec.EndpointSpec.Mode
+	}
+	if policy := spec.TaskTemplate.RestartPolicy; policy != nil {
+		deploy.RestartPolicy = &ComposeRestartPolicy{Condition: policy.Condition, Delay: formatComposeDuration(policy.Delay), MaxAttempts: policy.MaxAttempts, Window: formatComposeDuration(policy.Window)}
+	}
+
+	switch {
+	case spec.Mode.Global != nil:
+		deploy.Mode = "global"
+	case spec.Mode.GlobalJob != nil:
+		deploy.Mode = "global-job"
+	case spec.Mode.ReplicatedJob != nil:
+		deploy.Mode = "replicated-job"
+		replicas, maxConcurrent := spec.Mode.ReplicatedJob.TotalCompletions, spec.Mode.ReplicatedJob.MaxConcurrent
+		deploy.Replicas = &replicas
+		if maxConcurrent != replicas {
+			deploy.MaxConcurrent = &maxConcurrent
+		}
+	default:
+		replicas := spec.Mode.Replicated.Replicas
+		deploy.Replicas = &replicas
+	}
+	return deploy
+}
+
+func (receiver *composeExporter) volumes(mounts []Mount) []ComposeServiceVolume {
+	var result []ComposeServiceVolume
+	for _, mount := range mounts {
+		volume := ComposeServiceVolume{Type: mount.Type, Source: mount.Source, Target: mount.Target, ReadOnly: mount.ReadOnly}
+		switch {
+		case mount.Type == "bind" && mount.BindOptions != nil && mount.BindOptions.Propagation != "":
+			volume.Bind = &struct {
+				Propagation string `yaml:"propagation,omitempty"`
+			}{Propagation: mount.BindOptions.Propagation}
+		case mount.Type == "tmpfs" && mount.TmpfsOptions != nil && mount.TmpfsOptions.SizeBytes > 0:
+			volume.Tmpfs = &struct {
+				Size string `yaml:"size,omitempty"`
+			}{Size: formatMemoryBytes(mount.TmpfsOptions.SizeBytes)}
+		case mount.Type == "volume" && mount.Source != "":
+			key, named := receiver.key(mount.Source)
+			declared := ComposeVolume{}
+			if named {
+				declared.Name = mount.Source
+			}
+			if options := mount.VolumeOptions; options != nil {
+				if options.NoCopy {
+					volume.Volume = &struct {
+						NoCopy bool `yaml:"nocopy,omitempty"`
+					}{NoCopy: true}
+				}
+				if options.DriverConfig != nil {
+					declared.Driver, declared.DriverOpts = options.DriverConfig.Name, options.DriverConfig.Options
+				}
+				declared.Labels = withoutStackLabels(options.Labels)
+			}
+			receiver.file.Volumes[key] = declared
+			volume.Source = key
+		}
+		result = append(result, volume)
+	}
+	return result
+}
+
+// serviceNetworks 网络ID转换为 compose 中的 key，去掉部署时自动添加的服务名别名
+func (receiver *composeExporter) serviceNetworks(serviceKey string, attachments []NetworkAttachmentConfig) ComposeServiceNetworks {
+	if len(attachments) == 0 {
+		return nil
+	}
+	result := ComposeServiceNetworks{}
+	for _, attachment := range attachments {
+		network, exists := receiver.networks[attachment.Target]
+		if !exists {
+			network = networkInspectJson{Name: attachment.Target}
+		}
+
+		key, named := receiver.key(network.Name)
+		if _, declared := receiver.file.Networks[key]; !declared {
+			if named || network.Labels[StackNamespaceLabel] != receiver.namespace {
+				// 不属于该 stack 的网络
+				receiver.file.Networks[key] = ComposeNetwork{Name: network.Name, External: ComposeExternal{External: true}}
+			} else {
+				receiver.file.Networks[key] = ComposeNetwork{Driver: network.Driver, DriverOpts: network.Options, Attachable: network.Attachable, Internal: network.Internal, Labels: withoutStackLabels(network.Labels)}
+			}
+		}
+
+		var aliases []string
+		for _, alias := range attachment.Aliases {
+			if alias != serviceKey {
+				aliases = append(aliases, alias)
+			}
+		}
+		result[key] = ComposeServiceNetwork{Aliases: aliases}
+	}
+	return result
+}
+
+// fileObject 配置、密钥导出为 external
+func (receiver *composeExporter) fileObject(declared map[string]ComposeFileObject, name string, file ServiceConfigFileJson) ComposeServiceFile {
+	key, _ := receiver.key(name)
+	object := ComposeFileObject{External: ComposeExternal{External: true}}
+	if key != name {
+		object.Name = name
+	}
+	declared[key] = object
+
+	mode := file.Mode
+	return ComposeServiceFile{Source: key, Target: file.Name, UID: file.UID, GID: file.GID, Mode: &mode}
+}
+
+func formatComposeUpdateConfig(config *UpdateConfig) *ComposeUpdateConfig {
+	if config == nil {
+		return nil
+	}
+	parallelism := config.Parallelism
+	return &ComposeUpdateConfig{
+		Parallelism:     &parallelism,
+		Delay:           formatComposeDuration(config.Delay),
+		FailureAction:   config.FailureAction,
+		Monitor:         formatComposeDuration(config.Monitor),
+		MaxFailureRatio: config.MaxFailureRatio,
+		Order:           config.Order,
+	}
+}
+
+func formatComposeDuration(nanoseconds int64) string {
+	if nanoseconds <= 0 {
+		return ""
+	}
+	return time.Duration(nanoseconds).String()
+}
+
+func formatComposeCPUs(nanoCPUs int64) string {
+	if nanoCPUs <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(nanoCPUs)/1e9, 'f', -1, 64)
+}
+
+// formatMemoryBytes 字节转换为 512M、1G，不能整除时输出字节数
+func formatMemoryBytes(bytes int64) string {
+	if bytes <= 0 {
+		return ""
+	}
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if bytes%unit.size == 0 {
+			return strconv.FormatInt(bytes/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// withoutStackLabels 去掉部署 stack 时自动添加的标签
+func withoutStackLabels(labels map[string]string) ComposeMapping {
+	result := ComposeMapping{}
+	for key, value := range labels {
+		if key != StackNamespaceLabel && key != stackImageLabel {
+			result[key] = value
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,261 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testComposeFile = `
+version: "3.8"
+services:
+  api:
+    image: farseer/fops:${TAG:-latest}
+    command: ./fops --port 8888
+    environment:
+      - REGION=${REGION:?region is required}
+      - PRICE=$$5
+    ports:
+      - "${PORT}:8888"
+      - 9000-9001:9000-9001/udp
+    volumes:
+      - data:/data:ro
+      - ./conf:/app/conf
+    networks: [front]
+    configs:
+      - source: app
+        target: /app/app.yaml
+    deploy:
+      replicas: 3
+      resources:
+        limits: {cpus: "0.5", memory: 512M}
+      update_config: {parallelism: 2, delay: 10s, order: start-first}
+networks:
+  front:
+    driver: overlay
+volumes:
+  data:
+configs:
+  app:
+    content: "a: 1"
+`
+
+func TestParseCompose(t *testing.T) {
+	env := map[string]string{"REGION": "cn", "PORT": "8080"}
+	compose, err := ParseCompose([]byte(testComposeFile), ComposeLoadOptions{WorkingDir: "/srv/fops", Env: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := compose.ServiceSpec("fops", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containerSpec := spec.TaskTemplate.ContainerSpec
+	if spec.Name != "fops_api" || spec.Labels[StackNamespaceLabel] != "fops" || containerSpec.Image != "farseer/fops:latest" {
+		t.Fatalf("unexpected service %s %v %s", spec.Name, spec.Labels, containerSpec.Image)
+	}
+	if !reflect.DeepEqual(containerSpec.Args, []string{"./fops", "--port", "8888"}) {
+		t.Fatalf("unexpected args %v", containerSpec.Args)
+	}
+	if !reflect.DeepEqual(containerSpec.Env, []string{"PRICE=$5", "REGION=cn"}) {
+		t.Fatalf("unexpected env %v", containerSpec.Env)
+	}
+	wantPorts := []PortConfig{
+		{Protocol: "tcp", TargetPort: 8888, PublishedPort: 8080, PublishMode: "ingress"},
+		{Protocol: "udp", TargetPort: 9000, PublishedPort: 9000, PublishMode: "ingress"},
+		{Protocol: "udp", TargetPort: 9001, PublishedPort: 9001, PublishMode: "ingress"},
+	}
+	if !reflect.DeepEqual(spec.EndpointSpec.Ports, wantPorts) {
+		t.Fatalf("unexpected ports %+v", spec.EndpointSpec.Ports)
+	}
+	if mounts := containerSpec.Mounts; len(mounts) != 2 || mounts[0].Source != "fops_data" || !mounts[0].ReadOnly || mounts[1].Type != "bind" || mounts[1].Source != "/srv/fops/conf" {
+		t.Fatalf("unexpected mounts %+v", mounts)
+	}
+	if networks := spec.TaskTemplate.Networks; len(networks) != 1 || networks[0].Target != "fops_front" || networks[0].Aliases[0] != "api" {
+		t.Fatalf("unexpected networks %+v", networks)
+	}
+	if configs := containerSpec.Configs; len(configs) != 1 || configs[0].ConfigName != "fops_app" || configs[0].File.Name != "/app/app.yaml" {
+		t.Fatalf("unexpected configs %+v", configs)
+	}
+	if spec.Mode.Replicated.Replicas != 3 || spec.TaskTemplate.Resources.Limits.NanoCPUs != 5e8 || spec.TaskTemplate.Resources.Limits.MemoryBytes != 512*1024*1024 {
+		t.Fatalf("unexpected deploy %+v %+v", spec.Mode, spec.TaskTemplate.Resources)
+	}
+	if spec.UpdateConfig.Parallelism != 2 || spec.UpdateConfig.Delay != 10e9 {
+		t.Fatalf("unexpected update config %+v", spec.UpdateConfig)
+	}
+
+	delete(env, "REGION")
+	if _, err = ParseCompose([]byte(testComposeFile), ComposeLoadOptions{Env: env}); err == nil {
+		t.Fatal("expected missing required variable error")
+	}
+}
+
+func TestComposeExportRoundTrip(t *testing.T) {
+	compose, err := ParseCompose([]byte(testComposeFile), ComposeLoadOptions{WorkingDir: "/srv/fops", Env: map[string]string{"REGION": "cn", "PORT": "8080"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := compose.ServiceSpec("fops", "api")
+
+	exporter := newComposeExporter("fops", []networkInspectJson{{ID: "n1", Name: "fops_front", Driver: "overlay", Labels: map[string]string{StackNamespaceLabel: "fops"}}})
+	exported := spec
+	exported.TaskTemplate.Networks = []NetworkAttachmentConfig{{Target: "n1", Aliases: []string{"api"}}}
+	exporter.addService(exported)
+
+	data, err := exporter.file.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := ParseCompose(data, ComposeLoadOptions{WorkingDir: "/srv/fops", Env: map[string]string{}})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	respec, err := reparsed.ServiceSpec("fops", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec, respec) {
+		t.Fatalf("round trip changed the spec:\n%s\n%s", DiffServiceSpec(spec, respec), data)
+	}
+}
+
+func TestComposeFileObjectEnvironment(t *testing.T) {
+	data := []byte(`
+services:
+  api:
+    image: farseer/fops:${TAG}
+secrets:
+  token:
+    environment: API_TOKEN
+`)
+	t.Setenv("API_TOKEN", "from-process")
+	file, err := ParseCompose(data, ComposeLoadOptions{Env: map[string]string{"TAG": "v1", "API_TOKEN": "from-options"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与变量插值一致，读取 ComposeLoadOptions.Env
+	content, err := file.fileObjectContent(file.Secrets["token"])
+	if err != nil || string(content) != "from-options" {
+		t.Fatalf("fileObjectContent() = %q, %v", content, err)
+	}
+
+	delete(file.Env, "API_TOKEN")
+	if _, err = file.fileObjectContent(file.Secrets["token"]); err == nil {
+		t.Fatal("fileObjectContent() should fail when the variable is not in Env")
+	}
+
+	// 没有指定 Env 时读取当前进程的环境变量
+	file.Env = nil
+	if content, err = file.fileObjectContent(file.Secrets["token"]); err != nil || string(content) != "from-process" {
+		t.Fatalf("fileObjectContent() = %q, %v", content, err)
+	}
+}
+
+func TestComposeEnvironmentBareKeys(t *testing.T) {
+	data := []byte(`
+services:
+  list:
+    image: farseer/fops
+    environment:
+      - TOKEN
+      - MISSING
+      - EMPTY=
+  map:
+    image: farseer/fops
+    environment:
+      TOKEN:
+      MISSING:
+      EMPTY: ""
+`)
+	file, err := ParseCompose(data, ComposeLoadOptions{Env: map[string]string{"TOKEN": "se$cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只有 key 的项从 Env 读取，没有设置时忽略；显式的空值保留
+	want := ComposeMapping{"TOKEN": "se$cret", "EMPTY": ""}
+	for _, name := range []string{"list", "map"} {
+		if env := file.Services[name].Environment; !reflect.DeepEqual(env, want) {
+			t.Fatalf("%s: environment = %v, want %v", name, env, want)
+		}
+	}
+
+	// 没有指定 Env 时读取当前进程的环境变量
+	t.Setenv("TOKEN", "from-process")
+	if file, err = ParseCompose(data, ComposeLoadOptions{}); err != nil || file.Services["list"].Environment["TOKEN"] != "from-process" {
+		t.Fatalf("environment = %v, %v", file.Services["list"].Environment, err)
+	}
+}
+
+func TestComposeUpdateConfigDefault(t *testing.T) {
+	file, err := ParseCompose([]byte("services:\n  api:\n    image: farseer/fops\n"), ComposeLoadOptions{Env: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := file.ServiceSpec("fops", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与 docker stack deploy 一致：不设置 UpdateConfig，使用 daemon 的默认值
+	if spec.UpdateConfig != nil {
+		t.Fatalf("UpdateConfig = %+v, want nil", spec.UpdateConfig)
+	}
+}
+
+func TestComposeExportEscapesVariables(t *testing.T) {
+	data := []byte(`
+services:
+  api:
+    image: farseer/fops
+    hostname: "api-$${X}"
+    entrypoint: ["sh", "-c"]
+    command: ["echo $$HOME"]
+    environment: ["GREETING=hi $${USER} $$HOME"]
+    labels: {path: "$${X}/data"}
+    extra_hosts: ["db-$$HOME:10.0.0.1"]
+  job:
+    image: farseer/fops
+    deploy: {mode: replicated-job, replicas: 5, max_concurrent: 1}
+`)
+	env := map[string]string{"HOME": "/root", "USER": "root", "X": "x"}
+	compose, err := ParseCompose(data, ComposeLoadOptions{WorkingDir: "/srv/fops", Env: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := newComposeExporter("fops", nil)
+	specs := map[string]ServiceSpec{}
+	for _, name := range []string{"api", "job"} {
+		spec, err := compose.ServiceSpec("fops", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec.TaskTemplate.Networks = nil
+		specs[name] = spec
+		exporter.addService(spec)
+	}
+	if env := specs["api"].TaskTemplate.ContainerSpec.Env; !reflect.DeepEqual(env, []string{"GREETING=hi ${USER} $HOME"}) {
+		t.Fatalf("env = %v", env)
+	}
+	if mode := specs["job"].Mode.ReplicatedJob; mode == nil || mode.MaxConcurrent != 1 || mode.TotalCompletions != 5 {
+		t.Fatalf("job mode = %+v", mode)
+	}
+
+	// 导出后重新解析：$ 不会被再次插值，max_concurrent 保留
+	exported, err := exporter.file.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := ParseCompose(exported, ComposeLoadOptions{WorkingDir: "/srv/fops", Env: env})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, exported)
+	}
+	for name, spec := range specs {
+		respec, err := reparsed.ServiceSpec("fops", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		respec.TaskTemplate.Networks = nil
+		if !reflect.DeepEqual(spec, respec) {
+			t.Fatalf("round trip changed %s:\n%s\n%s", name, DiffServiceSpec(spec, respec), exported)
+		}
+	}
+}
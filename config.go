@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -17,9 +18,10 @@ type config struct {
 	api *dockerAPI
 }
 type ConfigCreateRequest struct {
-	Name   string            `json:"Name"`
-	Labels map[string]string `json:"Labels"`
-	Data   string            `json:"Data"` // 注意：发送给 API 时必须是 Base64 编码的字符串
+	Name       string            `json:"Name"`
+	Labels     map[string]string `json:"Labels"`
+	Data       string            `json:"Data"`                 // 注意：发送给 API 时必须是 Base64 编码的字符串
+	Templating *ConfigTemplating `json:"Templating,omitempty"` // 模板驱动，为空时内容原样挂载
 }
 
 type ConfigInfo struct {
@@ -121,3 +123,56 @@ func (receiver config) GetLastVersion(serviceName string) (ConfigInfo, error) {
 	result.Version = parse.ToInt(result.Spec.Labels["version"])
 	return result, err
 }
+
+// ConfigListOptions 查询配置的过滤条件，为空的条件不过滤
+type ConfigListOptions struct {
+	ID     string            // 配置ID（前缀匹配）
+	Name   string            // 配置名称（前缀匹配）
+	Labels map[string]string // 配置标签，value 为空时只要求存在该标签
+}
+
+// List 查询配置列表（Data 已解码为明文）
+func (receiver config) List(options ConfigListOptions) (collections.List[ConfigInfo], error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/configs?filters={"label":["owner_service=fops"]}
+	configsUrl := receiver.api.URL("/configs") + listFilters(options.ID, options.Name, options.Labels)
+	configs, err := UnixRequestDecode[collections.List[ConfigInfo]](context.Background(), receiver.api.httpClient, http.MethodGet, configsUrl, nil, nil)
+	if err != nil {
+		return collections.NewList[ConfigInfo](), err
+	}
+	configs.Foreach(func(item *ConfigInfo) {
+		if decodedByte, err := base64.StdEncoding.DecodeString(item.Spec.Data); err == nil {
+			item.Spec.Data = string(decodedByte)
+		}
+		item.Version = parse.ToInt(item.Spec.Labels["version"])
+	})
+	return configs, nil
+}
+
+// Remove 删除配置（被服务使用中的配置无法删除）
+func (receiver config) Remove(configIdOrName string) error {
+	// curl --unix-socket /var/run/docker.sock -X DELETE http://localhost/configs/fops_config_v1
+	_, err := UnixDelete(receiver.api.httpClient, receiver.api.URL(fmt.Sprintf("/configs/%s", configIdOrName)))
+	return err
+}
+
+// listFilters 配置、密钥列表的过滤参数 ?filters={"id":[..],"name":[..],"label":[..]}，没有条件时返回空字符串
+func listFilters(id string, name string, labels map[string]string) string {
+	filters := map[string][]string{}
+	if id != "" {
+		filters["id"] = append(filters["id"], id)
+	}
+	if name != "" {
+		filters["name"] = append(filters["name"], name)
+	}
+	for key, value := range labels {
+		if value != "" {
+			key += "=" + value
+		}
+		filters["label"] = append(filters["label"], key)
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	filter, _ := json.Marshal(filters)
+	return "?filters=" + url.QueryEscape(string(filter))
+}
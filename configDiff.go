@@ -0,0 +1,294 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置内容的格式
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+	ConfigFormatEnv  = "env"
+)
+
+// configDiffContext unified diff 每个差异块前后保留的行数
+const configDiffContext = 3
+
+// configDiffMaxCells 逐行比较的最大计算量（行数乘积），超过时整体作为删除+新增输出
+const configDiffMaxCells = 4_000_000
+
+// ConfigDiff 两个配置版本的内容差异
+type ConfigDiff struct {
+	OldName string          // 旧配置名称
+	NewName string          // 新配置名称
+	Format  string          // 两个版本都能解析时为 json yaml env，否则为空（只有文本差异）
+	Unified string          // unified 格式的文本差异，内容一致时为空
+	Keys    ServiceSpecDiff // 按 key 比较的差异（Field 为格式，Key 为路径 server.port、hosts[0]）
+}
+
+// HasChanges 内容是否存在差异
+func (receiver ConfigDiff) HasChanges() bool {
+	return receiver.Unified != ""
+}
+
+// Diff 比较两个配置的内容（oldConfig => newConfig，ID 或名称）
+func (receiver config) Diff(oldConfigIdOrName, newConfigIdOrName string) (ConfigDiff, error) {
+	oldConfig, err := receiver.Inspect(oldConfigIdOrName)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("inspect config %s: %w", oldConfigIdOrName, err)
+	}
+	newConfig, err := receiver.Inspect(newConfigIdOrName)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("inspect config %s: %w", newConfigIdOrName, err)
+	}
+	return DiffConfigContent(oldConfig.Spec.Name, oldConfig.Spec.Data, newConfig.Spec.Name, newConfig.Spec.Data), nil
+}
+
+// DiffVersions 比较服务两个版本的配置 fops_config_v2 => fops_config_v3
+func (receiver config) DiffVersions(serviceName string, oldVersion, newVersion int) (ConfigDiff, error) {
+	return receiver.Diff(ConfigVersionName(serviceName, oldVersion), ConfigVersionName(serviceName, newVersion))
+}
+
+// DiffConfigContent 比较两段配置内容，输出文本差异；两者都是 JSON、YAML 或 .env 时同时按 key 比较
+func DiffConfigContent(oldName, oldContent, newName, newContent string) ConfigDiff {
+	diff := ConfigDiff{OldName: oldName, NewName: newName}
+	if oldContent == newContent {
+		return diff
+	}
+	diff.Unified = unifiedDiff(oldName, newName, splitLines(oldContent), splitLines(newContent))
+
+	oldFormat, oldValues := parseConfigContent(oldContent)
+	newFormat, newValues := parseConfigContent(newContent)
+	if oldFormat != "" && oldFormat == newFormat {
+		diff.Format = oldFormat
+		diff.Keys.keyed(oldFormat, oldValues, newValues)
+	}
+	return diff
+}
+
+// envLinePattern .env 的一行 KEY=VALUE（允许 export 前缀）
+var envLinePattern = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*=(.*)$`)
+
+// parseConfigContent 识别内容的格式并展开为 路径 -> 值，无法识别时返回空格式
+func parseConfigContent(content string) (string, map[string]string) {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return "", nil
+	}
+
+	// 1. JSON
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err == nil && !decoder.More() {
+			values := map[string]string{}
+			flattenConfigValue("", value, values)
+			return ConfigFormatJSON, values
+		}
+	}
+
+	// 2. .env：每一行都是 KEY=VALUE、注释或空行
+	values := map[string]string{}
+	isEnv := true
+	for _, line := range strings.Split(trimmed, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := envLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			isEnv = false
+			break
+		}
+		values[match[1]] = strings.Trim(strings.TrimSpace(match[2]), `"'`)
+	}
+	if isEnv && len(values) > 0 {
+		return ConfigFormatEnv, values
+	}
+
+	// 3. YAML：只接受 map、数组，避免把普通文本当作 YAML 字符串
+	var value any
+	if err := yaml.Unmarshal([]byte(content), &value); err == nil {
+		switch value.(type) {
+		case map[string]any, map[any]any, []any:
+			values = map[string]string{}
+			flattenConfigValue("", value, values)
+			return ConfigFormatYAML, values
+		}
+	}
+	return "", nil
+}
+
+// flattenConfigValue 将嵌套的 map、数组展开为 a.b.c、a[0] 形式的路径
+func flattenConfigValue(path string, value any, values map[string]string) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch typed := value.(type) {
+	case map[string]any:
+		if len(typed) == 0 {
+			values[path] = "{}"
+		}
+		for key, item := range typed {
+			flattenConfigValue(join(key), item, values)
+		}
+	case map[any]any:
+		if len(typed) == 0 {
+			values[path] = "{}"
+		}
+		for key, item := range typed {
+			flattenConfigValue(join(fmt.Sprint(key)), item, values)
+		}
+	case []any:
+		if len(typed) == 0 {
+			values[path] = "[]"
+		}
+		for i, item := range typed {
+			flattenConfigValue(fmt.Sprintf("%s[%d]", path, i), item, values)
+		}
+	case nil:
+		values[path] = "null"
+	default:
+		values[path] = fmt.Sprint(typed)
+	}
+}
+
+// noNewlineMarker 最后一行没有换行时追加的标记（同 diff -u），使末尾换行的变化也能比较出差异
+const noNewlineMarker = "\n\\ No newline at end of file"
+
+// splitLines 按 \n 拆分，保留 \r；最后一行没有换行时追加 noNewlineMarker
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if !strings.HasSuffix(content, "\n") {
+		lines[len(lines)-1] += noNewlineMarker
+	}
+	return lines
+}
+
+// diffLine 逐行比较的结果：' ' 相同，'-' 删除，'+' 新增
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// diffLines 基于最长公共子序列逐行比较，先去掉相同的首尾
+func diffLines(oldLines, newLines []string) []diffLine {
+	var prefix, suffix []diffLine
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[0] == newLines[0] {
+		prefix = append(prefix, diffLine{' ', oldLines[0]})
+		oldLines, newLines = oldLines[1:], newLines[1:]
+	}
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[len(oldLines)-1] == newLines[len(newLines)-1] {
+		suffix = append([]diffLine{{' ', oldLines[len(oldLines)-1]}}, suffix...)
+		oldLines, newLines = oldLines[:len(oldLines)-1], newLines[:len(newLines)-1]
+	}
+
+	result := prefix
+	if len(oldLines)*len(newLines) > configDiffMaxCells {
+		// 内容太大：整体作为删除+新增
+		for _, line := range oldLines {
+			result = append(result, diffLine{'-', line})
+		}
+		for _, line := range newLines {
+			result = append(result, diffLine{'+', line})
+		}
+		return append(result, suffix...)
+	}
+
+	// lcs[i][j]：oldLines[i:] 与 newLines[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			result = append(result, diffLine{' ', oldLines[i]})
+			i, j = i+1, j+1
+		case j < len(newLines) && (i == len(oldLines) || lcs[i][j+1] > lcs[i+1][j]):
+			result = append(result, diffLine{'+', newLines[j]})
+			j++
+		default:
+			result = append(result, diffLine{'-', oldLines[i]})
+			i++
+		}
+	}
+	return append(result, suffix...)
+}
+
+// unifiedDiff 输出 unified 格式的差异（同 diff -u），内容一致时返回空字符串
+func unifiedDiff(oldName, newName string, oldLines, newLines []string) string {
+	lines := diffLines(oldLines, newLines)
+
+	// 每一行之前已经输出的旧、新行数
+	oldBefore, newBefore := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldBefore[i+1], newBefore[i+1] = oldBefore[i], newBefore[i]
+		if line.kind != '+' {
+			oldBefore[i+1]++
+		}
+		if line.kind != '-' {
+			newBefore[i+1]++
+		}
+	}
+	hunkStart := func(before, count int) int {
+		if count == 0 {
+			return before
+		}
+		return before + 1
+	}
+
+	var buffer bytes.Buffer
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+		// 相同的行超过 2*context 时拆分为新的差异块
+		last := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].kind != ' ' {
+				last = j
+			} else if j-last > 2*configDiffContext {
+				break
+			}
+		}
+		start, end := max(i-configDiffContext, 0), min(last+configDiffContext+1, len(lines))
+
+		if buffer.Len() == 0 {
+			buffer.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+		}
+		oldCount, newCount := oldBefore[end]-oldBefore[start], newBefore[end]-newBefore[start]
+		buffer.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", hunkStart(oldBefore[start], oldCount), oldCount, hunkStart(newBefore[start], newCount), newCount))
+		for _, line := range lines[start:end] {
+			buffer.WriteByte(line.kind)
+			buffer.WriteString(line.text)
+			buffer.WriteByte('\n')
+		}
+		i = end
+	}
+	return buffer.String()
+}
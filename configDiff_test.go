@@ -0,0 +1,63 @@
+package docker
+
+import "testing"
+
+func TestDiffConfigContent(t *testing.T) {
+	oldContent := "{\n  \"server\": {\"port\": 80, \"host\": \"0.0.0.0\"},\n  \"debug\": false\n}\n"
+	newContent := "{\n  \"server\": {\"port\": 8080, \"host\": \"0.0.0.0\"},\n  \"debug\": false,\n  \"hosts\": [\"a\"]\n}\n"
+
+	diff := DiffConfigContent("fops_config_v1", oldContent, "fops_config_v2", newContent)
+	wantUnified := "--- fops_config_v1\n+++ fops_config_v2\n@@ -1,4 +1,5 @@\n {\n" +
+		"-  \"server\": {\"port\": 80, \"host\": \"0.0.0.0\"},\n" +
+		"-  \"debug\": false\n" +
+		"+  \"server\": {\"port\": 8080, \"host\": \"0.0.0.0\"},\n" +
+		"+  \"debug\": false,\n" +
+		"+  \"hosts\": [\"a\"]\n" +
+		" }\n"
+	if diff.Unified != wantUnified {
+		t.Fatalf("got:\n%s\nwant:\n%s", diff.Unified, wantUnified)
+	}
+	wantKeys := "+ json hosts[0]: a\n~ json server.port: 80 => 8080\n"
+	if diff.Format != ConfigFormatJSON || diff.Keys.String() != wantKeys {
+		t.Fatalf("got %s:\n%s\nwant:\n%s", diff.Format, diff.Keys.String(), wantKeys)
+	}
+
+	envDiff := DiffConfigContent("a", "# db\nDB_HOST=db\nDB_PORT=5432\n", "b", "# db\nDB_HOST=db2\nDB_PORT=5432\n")
+	if envDiff.Format != ConfigFormatEnv || envDiff.Keys.String() != "~ env DB_HOST: db => db2\n" {
+		t.Fatalf("got %s:\n%s", envDiff.Format, envDiff.Keys.String())
+	}
+
+	yamlDiff := DiffConfigContent("a", "redis:\n  addr: 127.0.0.1\n", "b", "redis:\n  addr: 127.0.0.1\n  db: 2\n")
+	if yamlDiff.Format != ConfigFormatYAML || yamlDiff.Keys.String() != "+ yaml redis.db: 2\n" {
+		t.Fatalf("got %s:\n%s", yamlDiff.Format, yamlDiff.Keys.String())
+	}
+
+	if DiffConfigContent("a", "same", "b", "same").HasChanges() {
+		t.Fatal("expected no changes for identical content")
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	oldLines := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+	newLines := []string{"1", "two", "3", "4", "5", "6", "7", "8", "9", "10", "11"}
+	want := "--- a\n+++ b\n@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"
+	if got := unifiedDiff("a", "b", oldLines, newLines); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffConfigContentLineEndings(t *testing.T) {
+	// 只有末尾换行不同
+	diff := DiffConfigContent("a", "port: 80\n", "b", "port: 80")
+	want := "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-port: 80\n+port: 80\n\\ No newline at end of file\n"
+	if !diff.HasChanges() || diff.Unified != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", diff.Unified, want)
+	}
+
+	// 只有换行符不同（CRLF => LF），按 key 比较没有差异
+	diff = DiffConfigContent("a", "A=1\r\nB=2\r\n", "b", "A=1\nB=2\n")
+	want = "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-A=1\r\n-B=2\r\n+A=1\n+B=2\n"
+	if !diff.HasChanges() || diff.Unified != want || diff.Keys.String() != "" {
+		t.Fatalf("got:\n%q\nwant:\n%q", diff.Unified, want)
+	}
+}
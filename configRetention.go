@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultConfigRetention 每个服务默认保留的配置版本数
+const defaultConfigRetention = 3
+
+// ConfigRetentionOptions 清理历史配置的参数
+type ConfigRetentionOptions struct {
+	Keep          int      // 每个 owner_service 保留最新的 N 个版本，默认 3
+	OwnerServices []string // 只清理这些服务的配置，为空时清理所有带 owner_service 标签的配置
+	DryRun        bool     // 只输出清理计划，不删除
+}
+
+// ConfigRetentionItem 一个配置的清理结果
+type ConfigRetentionItem struct {
+	ID           string // 配置ID
+	Name         string // 配置名称
+	OwnerService string // 所属服务
+	Version      int    // 版本号
+	Reason       string // 保留、删除失败的原因
+}
+
+// ConfigRetentionResult 清理历史配置的结果
+type ConfigRetentionResult struct {
+	DryRun  bool                  // 是否只输出清理计划
+	Kept    []ConfigRetentionItem // 保留的配置
+	Removed []ConfigRetentionItem // 删除（DryRun 时为将要删除）的配置
+	Failed  []ConfigRetentionItem // 删除失败的配置
+}
+
+// String 输出清理计划，每行一个配置
+//
+//	  keep   fops_config_v5  newest
+//	  keep   fops_config_v2  referenced by fops (previous spec)
+//	- remove fops_config_v1
+func (receiver ConfigRetentionResult) String() string {
+	var builder strings.Builder
+	for _, item := range receiver.Kept {
+		builder.WriteString(fmt.Sprintf("  keep   %s  %s\n", item.Name, item.Reason))
+	}
+	for _, item := range receiver.Removed {
+		if receiver.DryRun {
+			builder.WriteString(fmt.Sprintf("- remove %s  (dry run)\n", item.Name))
+		} else {
+			builder.WriteString(fmt.Sprintf("- remove %s\n", item.Name))
+		}
+	}
+	for _, item := range receiver.Failed {
+		builder.WriteString(fmt.Sprintf("! failed %s  %s\n", item.Name, item.Reason))
+	}
+	return builder.String()
+}
+
+// Prune 清理历史版本的配置：每个 owner_service 保留最新的 Keep 个版本，
+// 以及仍被服务 Spec、PreviousSpec 引用的配置（回滚时需要）
+func (receiver config) Prune(options ConfigRetentionOptions) (ConfigRetentionResult, error) {
+	result := ConfigRetentionResult{DryRun: options.DryRun}
+	if options.Keep <= 0 {
+		options.Keep = defaultConfigRetention
+	}
+
+	// 1. 带 owner_service 标签的配置
+	configs, err := receiver.List(ConfigListOptions{Labels: map[string]string{ConfigOwnerLabel: ""}})
+	if err != nil {
+		return result, err
+	}
+	owners := map[string]bool{}
+	for _, owner := range options.OwnerServices {
+		owners[owner] = true
+	}
+	var candidates []ConfigInfo
+	configs.Foreach(func(item *ConfigInfo) {
+		if len(owners) == 0 || owners[item.Spec.Labels[ConfigOwnerLabel]] {
+			candidates = append(candidates, *item)
+		}
+	})
+
+	// 2. 服务引用的配置，查询失败时中止，避免删除仍被引用的配置
+	services, err := UnixRequestDecode[[]ServiceInspectJson](context.Background(), receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services"), nil, nil)
+	if err != nil {
+		return result, fmt.Errorf("list services failed: %w", err)
+	}
+	referenced := configReferences(services)
+
+	// 3. 删除
+	result.Kept, result.Removed = planConfigRetention(candidates, referenced, options.Keep)
+	if options.DryRun {
+		return result, nil
+	}
+	var errs []error
+	removed := result.Removed[:0]
+	for _, item := range result.Removed {
+		if err = receiver.Remove(item.ID); err != nil {
+			item.Reason = err.Error()
+			result.Failed = append(result.Failed, item)
+			errs = append(errs, fmt.Errorf("remove config %s: %w", item.Name, err))
+			continue
+		}
+		removed = append(removed, item)
+	}
+	result.Removed = removed
+	return result, errors.Join(errs...)
+}
+
+// configReferences 服务引用的配置：配置ID -> 引用原因
+func configReferences(services []ServiceInspectJson) map[string]string {
+	referenced := map[string]string{}
+	for _, svc := range services {
+		for _, item := range svc.PreviousSpec.TaskTemplate.ContainerSpec.Configs {
+			referenced[item.ConfigID] = fmt.Sprintf("referenced by %s (previous spec)", svc.Spec.Name)
+		}
+		// 当前配置的引用优先展示
+		for _, item := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+			referenced[item.ConfigID] = fmt.Sprintf("referenced by %s", svc.Spec.Name)
+		}
+	}
+	return referenced
+}
+
+// planConfigRetention 按 owner_service 分组，版本号倒序，保留最新的 keep 个以及被引用的配置
+func planConfigRetention(configs []ConfigInfo, referenced map[string]string, keep int) (kept []ConfigRetentionItem, removed []ConfigRetentionItem) {
+	sorted := append([]ConfigInfo(nil), configs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ownerI, ownerJ := sorted[i].Spec.Labels[ConfigOwnerLabel], sorted[j].Spec.Labels[ConfigOwnerLabel]
+		if ownerI != ownerJ {
+			return ownerI < ownerJ
+		}
+		return sorted[i].Version > sorted[j].Version
+	})
+
+	count := map[string]int{}
+	for _, info := range sorted {
+		owner := info.Spec.Labels[ConfigOwnerLabel]
+		item := ConfigRetentionItem{ID: info.ID, Name: info.Spec.Name, OwnerService: owner, Version: info.Version}
+		count[owner]++
+		switch reason, exists := referenced[info.ID]; {
+		case count[owner] <= keep:
+			item.Reason = "newest"
+			kept = append(kept, item)
+		case exists:
+			item.Reason = reason
+			kept = append(kept, item)
+		default:
+			removed = append(removed, item)
+		}
+	}
+	return kept, removed
+}
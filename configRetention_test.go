@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPlanConfigRetention(t *testing.T) {
+	newConfig := func(id string, owner string, version int) ConfigInfo {
+		return ConfigInfo{ID: id, Spec: ConfigCreateRequest{Name: ConfigVersionName(owner, version), Labels: map[string]string{ConfigOwnerLabel: owner}}, Version: version}
+	}
+	configs := []ConfigInfo{
+		newConfig("a1", "fops", 1), newConfig("a4", "fops", 4), newConfig("a2", "fops", 2), newConfig("a3", "fops", 3),
+		newConfig("b1", "fss", 1),
+	}
+	referenced := configReferences([]ServiceInspectJson{{
+		Spec:         ServiceSpec{Name: "fops", TaskTemplate: TaskSpec{ContainerSpec: ContainerSpec{Configs: []ServiceConfigJson{{ConfigID: "a4"}}}}},
+		PreviousSpec: ServiceSpec{Name: "fops", TaskTemplate: TaskSpec{ContainerSpec: ContainerSpec{Configs: []ServiceConfigJson{{ConfigID: "a2"}}}}},
+	}})
+
+	result := ConfigRetentionResult{DryRun: true}
+	result.Kept, result.Removed = planConfigRetention(configs, referenced, 1)
+	want := "  keep   fops_config_v4  newest\n" +
+		"  keep   fops_config_v2  referenced by fops (previous spec)\n" +
+		"  keep   fss_config_v1  newest\n" +
+		"- remove fops_config_v3  (dry run)\n" +
+		"- remove fops_config_v1  (dry run)\n"
+	if got := result.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConfigPruneAbortsWhenListingFails(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	for version := 1; version <= 5; version++ {
+		swarm.addObject("configs", ConfigVersionName("fops", version), "v", map[string]string{ConfigOwnerLabel: "fops"})
+	}
+
+	swarm.failures = map[string]int{"/services": http.StatusInternalServerError}
+	if _, err := client.Config.Prune(ConfigRetentionOptions{Keep: 1}); err == nil {
+		t.Fatal("expected prune to fail when services cannot be listed")
+	}
+	if names := swarm.objectNames("configs"); len(names) != 5 {
+		t.Fatalf("no config should be removed, got %v", names)
+	}
+
+	swarm.failures = map[string]int{"/configs": http.StatusInternalServerError}
+	_, err := client.Config.Publish("fops", []byte("v6"), nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected publish to return the daemon error, got %v", err)
+	}
+	if names := swarm.objectNames("configs"); len(names) != 5 {
+		t.Fatalf("no config should be created, got %v", names)
+	}
+}
@@ -0,0 +1,232 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/fs/parse"
+)
+
+// defaultConfigSyncInterval 两次服务更新的默认最小间隔
+const defaultConfigSyncInterval = 5 * time.Second
+
+// ConfigSyncOptions 配置同步的参数
+type ConfigSyncOptions struct {
+	MinInterval time.Duration                 // 两次服务更新的最小间隔（限流），默认 5s
+	OnResult    func(result ConfigSyncResult) // 每个服务同步后回调（包括无需更新、失败）
+}
+
+// ConfigSyncResult 一个服务的同步结果
+type ConfigSyncResult struct {
+	OwnerService string    // 配置的 owner_service 标签
+	ServiceName  string    // 服务名称
+	ConfigName   string    // 最新的配置名称 fops_config_v3
+	FromVersion  int       // 服务原来使用的版本
+	ToVersion    int       // 最新版本
+	Updated      bool      // 是否更新了服务
+	Err          error     // 同步失败的原因
+	SyncedAt     time.Time // 同步时间
+}
+
+// ConfigSyncController 监听 config 创建事件，将 owner_service 匹配的服务更新到最新版本的配置
+type ConfigSyncController struct {
+	client     *Client
+	options    ConfigSyncOptions
+	mu         sync.Mutex
+	running    bool            // Run 是否在运行，没有运行时丢弃事件
+	queue      []string        // 等待处理的配置ID
+	queued     map[string]bool // 已在队列中的配置ID
+	notify     chan struct{}   // 有新的配置
+	syncMu     sync.Mutex      // 同一时间只同步一个 owner_service
+	lastUpdate time.Time       // 上一次更新服务的时间
+}
+
+// NewConfigSyncController 创建配置同步控制器，调用 Run 后开始处理事件
+func (receiver *Client) NewConfigSyncController(options ConfigSyncOptions) *ConfigSyncController {
+	if options.MinInterval <= 0 {
+		options.MinInterval = defaultConfigSyncInterval
+	}
+	return &ConfigSyncController{
+		client:  receiver,
+		options: options,
+		queued:  map[string]bool{},
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Handle 实现 EventHandler 接口：只处理 config create 事件，不阻塞事件的分发；Run 没有运行时丢弃事件
+func (receiver *ConfigSyncController) Handle(event EventResult) {
+	if event.Type != "config" || event.Action != "create" || event.Actor.ID == "" {
+		return
+	}
+	receiver.mu.Lock()
+	if !receiver.running || receiver.queued[event.Actor.ID] {
+		receiver.mu.Unlock()
+		return
+	}
+	receiver.queue = append(receiver.queue, event.Actor.ID)
+	receiver.queued[event.Actor.ID] = true
+	receiver.mu.Unlock()
+
+	select {
+	case receiver.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run 注册到 Client.Event 并处理配置创建事件，直到 ctx 取消（退出时移除注册并清空队列）
+// 事件来自 Client.Event，需要调用 Client.Event.Start() 启动监听
+func (receiver *ConfigSyncController) Run(ctx context.Context) error {
+	receiver.mu.Lock()
+	if receiver.running {
+		receiver.mu.Unlock()
+		return errors.New("config sync controller is already running")
+	}
+	receiver.running = true
+	receiver.mu.Unlock()
+
+	receiver.client.Event.Register(receiver)
+	defer func() {
+		receiver.client.Event.Unregister(receiver)
+		receiver.mu.Lock()
+		receiver.running, receiver.queue, receiver.queued = false, nil, map[string]bool{}
+		receiver.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-receiver.notify:
+		}
+
+		// 一次取出队列中的所有配置，同一个 owner_service 只同步一次
+		receiver.mu.Lock()
+		configIds := receiver.queue
+		receiver.queue, receiver.queued = nil, map[string]bool{}
+		receiver.mu.Unlock()
+
+		var owners []string
+		for _, configId := range configIds {
+			info, err := receiver.client.Config.Inspect(configId)
+			if err != nil {
+				// 配置已被删除
+				continue
+			}
+			if owner := info.Spec.Labels[ConfigOwnerLabel]; owner != "" && !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+		for _, owner := range owners {
+			if err := receiver.sync(ctx, owner, receiver.report); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Sync 立即将 owner_service 匹配的服务更新到最新版本的配置，返回每个服务的结果
+func (receiver *ConfigSyncController) Sync(ctx context.Context, ownerService string) ([]ConfigSyncResult, error) {
+	var results []ConfigSyncResult
+	err := receiver.sync(ctx, ownerService, func(result ConfigSyncResult) {
+		receiver.report(result)
+		results = append(results, result)
+	})
+	return results, err
+}
+
+// sync 同步 ownerService 的所有服务，只有 ctx 取消时返回错误，其它错误通过 report 报告
+func (receiver *ConfigSyncController) sync(ctx context.Context, ownerService string, report func(result ConfigSyncResult)) error {
+	receiver.syncMu.Lock()
+	defer receiver.syncMu.Unlock()
+	notify := report
+	report = func(result ConfigSyncResult) {
+		result.SyncedAt = time.Now()
+		notify(result)
+	}
+
+	// 处理时再读取最新版本：短时间内创建多个版本时只更新一次
+	latest, err := receiver.client.Config.GetLastVersion(ownerService)
+	if err != nil || latest.ID == "" {
+		if err == nil {
+			err = fmt.Errorf("no config found for %s", ownerService)
+		}
+		report(ConfigSyncResult{OwnerService: ownerService, Err: err})
+		return nil
+	}
+
+	services, err := receiver.ownerServices(ownerService)
+	if err != nil {
+		report(ConfigSyncResult{OwnerService: ownerService, ConfigName: latest.Spec.Name, ToVersion: latest.Version, Err: err})
+		return nil
+	}
+	prefix := ownerService + "_config_v"
+	for _, svc := range services {
+		serviceName := svc.Spec.Name
+		result := ConfigSyncResult{OwnerService: ownerService, ServiceName: serviceName, ConfigName: latest.Spec.Name, ToVersion: latest.Version}
+
+		// 服务挂载的版本化配置：同一系列可能挂载到多个路径，FromVersion 为其中最旧的版本
+		var update ServiceFilesUpdate
+		for _, item := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+			if !strings.HasPrefix(item.ConfigName, prefix) {
+				continue
+			}
+			if version := parse.ToInt(item.ConfigName[len(prefix):]); result.FromVersion == 0 || version < result.FromVersion {
+				result.FromVersion = version
+			}
+			if item.ConfigID != latest.ID {
+				// 只指定路径，保留原有权限
+				update.SetConfigs = append(update.SetConfigs, ServiceConfigJson{ConfigID: latest.ID, ConfigName: latest.Spec.Name, File: ServiceConfigFileJson{Name: item.File.Name}})
+			}
+		}
+		if update.IsEmpty() || result.FromVersion >= latest.Version {
+			// 没有使用该配置，或者已经是最新版本
+			report(result)
+			continue
+		}
+
+		// 限流：两次更新之间至少间隔 MinInterval
+		if wait := time.Until(receiver.lastUpdate.Add(receiver.options.MinInterval)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		// 一次更新替换所有挂载，只触发一次滚动更新
+		_, result.Err = receiver.client.Service.UpdateFilesContext(ctx, serviceName, update)
+		result.Updated = result.Err == nil
+		receiver.lastUpdate = time.Now()
+		report(result)
+	}
+	return nil
+}
+
+// ownerServices 名称为 ownerService，或者带有 owner_service=ownerService 标签的服务
+func (receiver *ConfigSyncController) ownerServices(ownerService string) ([]ServiceInspectJson, error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/services
+	services, err := UnixRequestDecode[[]ServiceInspectJson](context.Background(), receiver.client.api.httpClient, http.MethodGet, receiver.client.api.URL("/services"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ServiceInspectJson
+	for _, svc := range services {
+		if svc.Spec.Name == ownerService || svc.Spec.Labels[ConfigOwnerLabel] == ownerService {
+			result = append(result, svc)
+		}
+	}
+	return result, nil
+}
+
+func (receiver *ConfigSyncController) report(result ConfigSyncResult) {
+	if receiver.options.OnResult != nil {
+		receiver.options.OnResult(result)
+	}
+}
@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConfigSyncController(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	owner := func(version int) map[string]string {
+		return map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: fmt.Sprint(version)}
+	}
+	v1 := swarm.addObject("configs", "fops_config_v1", "v: 1", owner(1))
+	swarm.addObject("configs", "fops_config_v2", "v: 2", owner(2))
+	other := swarm.addObject("configs", "other_config_v1", "v: 1", map[string]string{ConfigOwnerLabel: "other", ConfigVersionLabel: "1"})
+
+	mount := ServiceConfigJson{ConfigID: v1, ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml", UID: "0", GID: "0", Mode: 0444}}
+	addService := func(name string, labels map[string]string, configs ...ServiceConfigJson) {
+		builder := NewServiceSpec(name, "farseer/fops:v1")
+		for key, value := range labels {
+			builder.Label(key, value)
+		}
+		for _, item := range configs {
+			builder.Config(item)
+		}
+		spec, err := builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		swarm.addService(spec)
+	}
+	addService("fops", nil, mount)                                                // 名称匹配
+	addService("fops-worker", map[string]string{ConfigOwnerLabel: "fops"}, mount) // 标签匹配
+	addService("fops-api", map[string]string{ConfigOwnerLabel: "fops"})           // 没有挂载版本化配置
+	addService("unrelated", nil, mount)                                           // 挂载了 fops 的配置，但不属于 fops
+	mounted := func(name string) string {
+		return swarm.service(name).Spec.TaskTemplate.ContainerSpec.Configs[0].ConfigName
+	}
+
+	results := make(chan ConfigSyncResult, 16)
+	controller := client.NewConfigSyncController(ConfigSyncOptions{
+		MinInterval: 100 * time.Millisecond,
+		OnResult:    func(result ConfigSyncResult) { results <- result },
+	})
+
+	// 1. Sync：只更新 owner_service 匹配的服务，两次更新之间至少间隔 MinInterval
+	synced, err := controller.Sync(context.Background(), "fops")
+	if err != nil || len(synced) != 3 {
+		t.Fatalf("Sync() = %+v, %v", synced, err)
+	}
+	var updatedAt []time.Time
+	for _, result := range synced {
+		<-results
+		switch result.ServiceName {
+		case "fops", "fops-worker":
+			if !result.Updated || result.Err != nil || result.FromVersion != 1 || result.ToVersion != 2 {
+				t.Fatalf("result = %+v", result)
+			}
+			updatedAt = append(updatedAt, result.SyncedAt)
+		case "fops-api":
+			if result.Updated || result.Err != nil {
+				t.Fatalf("result = %+v", result)
+			}
+		default:
+			t.Fatalf("unexpected service %s", result.ServiceName)
+		}
+	}
+	if len(updatedAt) != 2 || updatedAt[1].Sub(updatedAt[0]) < 100*time.Millisecond {
+		t.Fatalf("updates are not rate limited: %v", updatedAt)
+	}
+	if mounted("fops") != "fops_config_v2" || mounted("fops-worker") != "fops_config_v2" || mounted("unrelated") != "fops_config_v1" {
+		t.Fatalf("mounted = %s %s %s", mounted("fops"), mounted("fops-worker"), mounted("unrelated"))
+	}
+
+	// 2. Run：同一批事件中同一个 owner_service 只同步一次
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- controller.Run(ctx) }()
+	waitFor(t, func() bool {
+		client.Event.mu.RLock()
+		defer client.Event.mu.RUnlock()
+		return len(client.Event.handlers) == 1
+	})
+	if err = controller.Run(ctx); err == nil {
+		t.Fatal("Run() should fail while already running")
+	}
+
+	created := func(configId string) EventResult {
+		event := EventResult{Type: "config", Action: "create"}
+		event.Actor.ID = configId
+		return event
+	}
+	// 阻塞 daemon，让后面的事件进入同一批
+	swarm.mu.Lock()
+	controller.Handle(created(other))
+	waitFor(t, func() bool {
+		controller.mu.Lock()
+		defer controller.mu.Unlock()
+		return len(controller.queue) == 0
+	})
+	v3 := swarm.createObject("configs", ConfigCreateRequest{Name: "fops_config_v3", Labels: owner(3)})
+	v4 := swarm.createObject("configs", ConfigCreateRequest{Name: "fops_config_v4", Labels: owner(4)})
+	controller.Handle(created(v3))
+	controller.Handle(created(v4))
+	controller.Handle(created(v3))
+	controller.Handle(EventResult{Type: "service", Action: "update"})
+	swarm.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-results:
+			if result.OwnerService != "fops" || result.ToVersion != 4 {
+				t.Fatalf("result = %+v", result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for sync results")
+		}
+	}
+	select {
+	case result := <-results:
+		t.Fatalf("owner synced more than once: %+v", result)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if mounted("fops") != "fops_config_v4" || mounted("fops-worker") != "fops_config_v4" || mounted("unrelated") != "fops_config_v1" {
+		t.Fatalf("mounted = %s %s %s", mounted("fops"), mounted("fops-worker"), mounted("unrelated"))
+	}
+
+	// 3. Run 退出后移除注册，不再接收事件
+	cancel()
+	if err = <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v", err)
+	}
+	if len(client.Event.handlers) != 0 {
+		t.Fatalf("handlers = %d, want 0", len(client.Event.handlers))
+	}
+	controller.Handle(created(v4))
+	if len(controller.queue) != 0 {
+		t.Fatalf("queue = %v, want empty", controller.queue)
+	}
+}
+
+// waitFor 等待 condition 成立，最多 5s
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestConfigSyncReplacesAllMounts(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	owner := func(version int) map[string]string {
+		return map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: fmt.Sprint(version)}
+	}
+	v1 := swarm.addObject("configs", "fops_config_v1", "v: 1", owner(1))
+	v2 := swarm.addObject("configs", "fops_config_v2", "v: 2", owner(2))
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Config(ServiceConfigJson{ConfigID: v1, ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml", UID: "0", GID: "0", Mode: 0400}}).
+		Config(ServiceConfigJson{ConfigID: v2, ConfigName: "fops_config_v2", File: ServiceConfigFileJson{Name: "/etc/fops.yaml", UID: "0", GID: "0", Mode: 0444}}).
+		Config(ServiceConfigJson{ConfigID: v1, ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/backup.yaml", UID: "1000", GID: "1000", Mode: 0440}}).
+		Build()
+	swarm.addService(spec)
+
+	var reported []ConfigSyncResult
+	controller := client.NewConfigSyncController(ConfigSyncOptions{OnResult: func(result ConfigSyncResult) { reported = append(reported, result) }})
+	synced, err := controller.Sync(context.Background(), "fops")
+	if err != nil || len(synced) != 1 || !synced[0].Updated || synced[0].FromVersion != 1 || synced[0].ToVersion != 2 {
+		t.Fatalf("Sync() = %+v, %v", synced, err)
+	}
+	// 同一系列的所有挂载一次更新全部替换，权限保持不变
+	if len(swarm.updates) != 1 {
+		t.Fatalf("updates = %v", swarm.updates)
+	}
+	configs := swarm.service("fops").Spec.TaskTemplate.ContainerSpec.Configs
+	for _, item := range configs {
+		if item.ConfigID != v2 || item.ConfigName != "fops_config_v2" {
+			t.Fatalf("configs = %+v", configs)
+		}
+	}
+	if configs[0].File.Mode != 0400 || configs[2].File.UID != "1000" || configs[2].File.Mode != 0440 {
+		t.Fatalf("permissions changed: %+v", configs)
+	}
+
+	// 查询服务失败时通过 OnResult 报告，而不是当作没有服务
+	swarm.failures = map[string]int{"/services": http.StatusInternalServerError}
+	reported = nil
+	if synced, err = controller.Sync(context.Background(), "fops"); err != nil || len(synced) != 1 || synced[0].Err == nil {
+		t.Fatalf("Sync() = %+v, %v", synced, err)
+	}
+	var apiErr *APIError
+	if len(reported) != 1 || !errors.As(reported[0].Err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("reported = %+v", reported)
+	}
+}
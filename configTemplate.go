@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ConfigTemplateDriverGolang swarm 内置的 go template 模板驱动
+const ConfigTemplateDriverGolang = "golang"
+
+// ConfigTemplating 配置、密钥的模板驱动
+type ConfigTemplating struct {
+	Name    string            `json:"Name"`
+	Options map[string]string `json:"Options,omitempty"`
+}
+
+// ConfigTemplateContext 渲染模板的上下文，与 swarm 在任务启动时提供的一致
+//
+//	{{.Service.Name}} {{.Task.Slot}} {{.Node.Hostname}} {{env "DB_HOST"}} {{secret "db_password"}} {{config "app.json"}}
+type ConfigTemplateContext struct {
+	Service ConfigTemplateService
+	Node    ConfigTemplateNode
+	Task    ConfigTemplateTask
+	Env     map[string]string // 容器的环境变量，env 函数读取
+	Secrets map[string]string // 挂载路径 -> 内容，secret 函数读取
+	Configs map[string]string // 挂载路径 -> 内容，config 函数读取
+}
+
+type ConfigTemplateService struct {
+	ID     string
+	Name   string
+	Labels map[string]string
+}
+
+type ConfigTemplateNode struct {
+	ID       string
+	Hostname string
+	Platform struct {
+		Architecture string
+		OS           string
+	}
+}
+
+type ConfigTemplateTask struct {
+	ID   string
+	Name string // fops.1.kbo9xu9qtxw1b69r02tt57bvh
+	Slot string // swarm 中 Slot 为字符串，全局服务为节点ID
+}
+
+// CreateTemplate 创建使用 go template 渲染的配置，任务启动时由 swarm 根据服务、任务、节点渲染
+func (receiver config) CreateTemplate(name string, content []byte, labels map[string]string) (string, error) {
+	// curl --unix-socket /var/run/docker.sock -X POST -d '{"Name":"fops_config_v1","Data":"base64","Templating":{"Name":"golang"}}' http://localhost/configs/create
+	data := ConfigCreateRequest{
+		Name:       name,
+		Labels:     labels,
+		Data:       base64.StdEncoding.EncodeToString(content), // 必须 Base64
+		Templating: &ConfigTemplating{Name: ConfigTemplateDriverGolang},
+	}
+	result, err := UnixPostJsonDecode[struct{ ID string }](receiver.api.httpClient, receiver.api.URL("/configs/create"), data, nil)
+	if err != nil {
+		return "", fmt.Errorf("create config %s failed: %w", name, err)
+	}
+	return result.ID, nil
+}
+
+// RenderConfigTemplate 在本地按 swarm 的规则渲染模板，用于发布前预览、校验
+// 与 swarm 一致：缺少的字段、secret、config 会返回错误，env 缺少时为空字符串
+func RenderConfigTemplate(content string, ctx ConfigTemplateContext) (string, error) {
+	tmpl, err := template.New("config").Option("missingkey=error").Funcs(template.FuncMap{
+		"env": func(key string) string {
+			return ctx.Env[key]
+		},
+		"secret": func(target string) (string, error) {
+			if value, exists := ctx.Secrets[target]; exists {
+				return value, nil
+			}
+			return "", fmt.Errorf("secret target %s not found", target)
+		},
+		"config": func(target string) (string, error) {
+			if value, exists := ctx.Configs[target]; exists {
+				return value, nil
+			}
+			return "", fmt.Errorf("config target %s not found", target)
+		},
+	}).Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if err = tmpl.Execute(&builder, ctx); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// TemplateContext 根据服务当前的配置生成渲染模板的上下文，slot 对应的任务存在时补全任务、节点信息
+// global 服务的 slot 传 0，使用第一个运行中的任务
+// 密钥的内容无法读取，需要调用方填充 Secrets
+func (receiver service) TemplateContext(serviceName string, slot int) (ConfigTemplateContext, error) {
+	svc, err := receiver.Inspect(serviceName)
+	if err != nil {
+		return ConfigTemplateContext{}, err
+	}
+	ctx := ConfigTemplateContext{
+		Service: ConfigTemplateService{ID: svc.ID, Name: svc.Spec.Name, Labels: svc.Spec.Labels},
+		Task:    ConfigTemplateTask{Slot: strconv.Itoa(slot)},
+		Env:     envToMap(svc.Spec.TaskTemplate.ContainerSpec.Env),
+		Secrets: map[string]string{},
+		Configs: map[string]string{},
+	}
+
+	// 挂载的配置（模板中按挂载路径引用）
+	for _, item := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+		info, err := (config{api: receiver.api}).Inspect(item.ConfigID)
+		if err != nil {
+			return ctx, fmt.Errorf("inspect config %s failed: %w", item.ConfigName, err)
+		}
+		ctx.Configs[item.File.Name] = info.Spec.Data
+	}
+
+	// 正在运行的任务
+	tasks, err := receiver.serviceTasks(context.Background(), svc.ID)
+	if err != nil {
+		return ctx, err
+	}
+	for _, item := range tasks {
+		if item.Slot != slot || item.DesiredState != "running" {
+			continue
+		}
+		ctx.Task.ID = item.ID
+		ctx.Task.Name = fmt.Sprintf("%s.%d.%s", svc.Spec.Name, slot, item.ID)
+		if slot == 0 {
+			// global 服务的任务没有 slot，swarm 使用节点ID命名：<service>.<nodeID>.<taskID>
+			ctx.Task.Name = fmt.Sprintf("%s.%s.%s", svc.Spec.Name, item.NodeID, item.ID)
+		}
+		nodeInfo := node{api: receiver.api}.Info(item.NodeID)
+		ctx.Node.ID = item.NodeID
+		ctx.Node.Hostname = nodeInfo.Description.Hostname
+		ctx.Node.Platform.Architecture = nodeInfo.Description.Platform.Architecture
+		ctx.Node.Platform.OS = nodeInfo.Description.Platform.OS
+		break
+	}
+	return ctx, nil
+}
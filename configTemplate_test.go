@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRenderConfigTemplate(t *testing.T) {
+	ctx := ConfigTemplateContext{
+		Service: ConfigTemplateService{Name: "fops"},
+		Task:    ConfigTemplateTask{Slot: "2"},
+		Env:     map[string]string{"DB_HOST": "db"},
+		Secrets: map[string]string{"db_password": "123456"},
+	}
+	ctx.Node.Hostname = "node-1"
+
+	got, err := RenderConfigTemplate(`{{.Service.Name}}.{{.Task.Slot}}@{{.Node.Hostname}} {{env "DB_HOST"}}:{{secret "db_password"}}{{env "MISSING"}}`, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "fops.2@node-1 db:123456"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err = RenderConfigTemplate(`{{secret "api_key"}}`, ctx); err == nil {
+		t.Fatal("expected an error for a missing secret")
+	}
+	if _, err = RenderConfigTemplate(`{{.Service.Image}}`, ctx); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+}
+
+func TestTemplateContext(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	configId := swarm.addObject("configs", "fops_config_v1", "a: 1", nil)
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Config(ServiceConfigJson{ConfigID: configId, ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml"}}).
+		Build()
+	svc := swarm.addService(spec)
+	swarm.tasks = []ServiceIdInspectJson{
+		{ID: "task1", ServiceID: svc.ID, Slot: 2, NodeID: "node1", DesiredState: "shutdown"},
+		{ID: "task2", ServiceID: svc.ID, Slot: 2, NodeID: "node2", DesiredState: "running"},
+	}
+
+	ctx, err := client.Service.TemplateContext("fops", 2)
+	if err != nil || ctx.Task.ID != "task2" || ctx.Task.Name != "fops.2.task2" || ctx.Node.ID != "node2" || ctx.Configs["/app/farseer.yaml"] == "" {
+		t.Fatalf("TemplateContext() = %#v, %v", ctx, err)
+	}
+
+	// global 服务的任务 slot 为 0，名称中使用节点ID
+	swarm.tasks = []ServiceIdInspectJson{{ID: "task3", ServiceID: svc.ID, NodeID: "node3", DesiredState: "running"}}
+	if ctx, err = client.Service.TemplateContext("fops", 0); err != nil || ctx.Task.Name != "fops.node3.task3" {
+		t.Fatalf("TemplateContext(global) = %#v, %v", ctx, err)
+	}
+
+	// 挂载的配置无法读取时返回错误，而不是渲染出缺少配置的模板
+	swarm.failures = map[string]int{"/configs/" + configId: http.StatusInternalServerError}
+	if _, err = client.Service.TemplateContext("fops", 2); err == nil {
+		t.Fatal("expected an error when the config cannot be inspected")
+	}
+}
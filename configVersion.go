@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/fs/parse"
+)
+
+// 版本化配置使用的标签
+const (
+	ConfigOwnerLabel   = "owner_service" // 所属服务
+	ConfigVersionLabel = "version"       // 版本号
+	ConfigHashLabel    = "content_hash"  // 内容的 sha256
+)
+
+// ConfigPublishResult 发布配置的结果
+type ConfigPublishResult struct {
+	ID      string // 配置ID
+	Name    string // 配置名称 fops_config_v3
+	Version int    // 版本号
+	Skipped bool   // 内容与最新版本一致，未创建新版本
+}
+
+// ConfigVersionName 版本化配置的名称：fops_config_v3
+func ConfigVersionName(serviceName string, version int) string {
+	return fmt.Sprintf("%s_config_v%d", serviceName, version)
+}
+
+// ConfigContentHash 配置内容的 sha256
+func ConfigContentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// Versions 服务的所有版本化配置，按版本号倒序
+func (receiver config) Versions(serviceName string) (collections.List[ConfigInfo], error) {
+	configs, err := receiver.List(ConfigListOptions{Labels: map[string]string{ConfigOwnerLabel: serviceName}})
+	if err != nil {
+		return configs, err
+	}
+	return configs.OrderByDescending(func(item ConfigInfo) any {
+		return item.Version
+	}).ToList(), nil
+}
+
+// GetVersion 获取服务指定版本的配置（Data 已解码）
+func (receiver config) GetVersion(serviceName string, version int) (ConfigInfo, error) {
+	result, err := receiver.Inspect(ConfigVersionName(serviceName, version))
+	if err != nil {
+		return result, err
+	}
+	result.Version = parse.ToInt(result.Spec.Labels[ConfigVersionLabel])
+	return result, nil
+}
+
+// Publish 发布服务的新版本配置 <service>_config_v<N>
+// 内容与最新版本一致时不创建新版本，返回最新版本（Skipped=true）
+func (receiver config) Publish(serviceName string, content []byte, labels map[string]string) (ConfigPublishResult, error) {
+	versions, err := receiver.Versions(serviceName)
+	if err != nil {
+		return ConfigPublishResult{}, err
+	}
+
+	// 1. 与最新版本比较内容
+	hash := ConfigContentHash(content)
+	if versions.Count() > 0 {
+		latest := versions.First()
+		latestHash := latest.Spec.Labels[ConfigHashLabel]
+		if latestHash == "" {
+			// 没有 hash 标签的旧配置，比较内容
+			latestHash = ConfigContentHash([]byte(latest.Spec.Data))
+		}
+		if latestHash == hash {
+			return ConfigPublishResult{ID: latest.ID, Name: latest.Spec.Name, Version: latest.Version, Skipped: true}, nil
+		}
+	}
+
+	// 2. 创建下一个版本（Versions 已按版本号倒序）
+	version := 1
+	if versions.Count() > 0 {
+		version = versions.First().Version + 1
+	}
+	configLabels := map[string]string{}
+	for key, value := range labels {
+		configLabels[key] = value
+	}
+	configLabels[ConfigOwnerLabel] = serviceName
+	configLabels[ConfigVersionLabel] = strconv.Itoa(version)
+	configLabels[ConfigHashLabel] = hash
+
+	name := ConfigVersionName(serviceName, version)
+	id, err := receiver.Create(name, content, configLabels)
+	if err != nil {
+		return ConfigPublishResult{}, err
+	}
+	return ConfigPublishResult{ID: id, Name: name, Version: version}, nil
+}
+
+// UseConfigVersion 将服务挂载的版本化配置（<service>_config_vN）切换到指定版本，可用于回滚
+func (receiver *Client) UseConfigVersion(serviceName string, version int) error {
+	target, err := receiver.Config.GetVersion(serviceName, version)
+	if err != nil {
+		return err
+	}
+
+	svc, err := receiver.Service.Inspect(serviceName)
+	if err != nil {
+		return err
+	}
+	prefix := serviceName + "_config_v"
+	for _, item := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+		if !strings.HasPrefix(item.ConfigName, prefix) {
+			continue
+		}
+		if item.ConfigName == target.Spec.Name {
+			return nil
+		}
+		_, err = receiver.Service.UpdateServiceConfig(serviceName, target.ID, target.Spec.Name, item.File.Name)
+		return err
+	}
+	return fmt.Errorf("service %s has no versioned config mounted", serviceName)
+}
+
+// RollbackConfig 将服务的配置回滚到上一个版本，返回回滚后的版本号
+func (receiver *Client) RollbackConfig(serviceName string) (int, error) {
+	curVersion, err := receiver.Service.GetCurConfigVersion(serviceName)
+	if err != nil {
+		return 0, err
+	}
+	if curVersion == 0 {
+		return 0, fmt.Errorf("service %s has no versioned config mounted", serviceName)
+	}
+
+	versions, err := receiver.Config.Versions(serviceName)
+	if err != nil {
+		return 0, err
+	}
+	// 比当前版本小的最新版本（中间的版本可能已被清理）
+	previous := versions.Where(func(item ConfigInfo) bool {
+		return item.Version < curVersion
+	}).First()
+	if previous.ID == "" {
+		return 0, fmt.Errorf("service %s has no config version before v%d", serviceName, curVersion)
+	}
+	return previous.Version, receiver.UseConfigVersion(serviceName, previous.Version)
+}
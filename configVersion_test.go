@@ -0,0 +1,325 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSwarmObject 模拟 daemon 保存的 config、secret
+type fakeSwarmObject struct {
+	ID   string
+	Spec ConfigCreateRequest
+}
+
+// fakeSwarmService 模拟 daemon 保存的服务
+type fakeSwarmService struct {
+	ID      string
+	Version int
+	Spec    ServiceSpec
+}
+
+// fakeSwarm 使用 httptest 模拟 swarm 的 configs、secrets、services 接口
+type fakeSwarm struct {
+	t        *testing.T
+	mu       sync.Mutex
+	nextId   int
+	configs  []*fakeSwarmObject
+	secrets  []*fakeSwarmObject
+	services []*fakeSwarmService
+	tasks    []ServiceIdInspectJson // GET /tasks 返回的任务
+	updates  []string               // 更新过的服务名称
+	requests []string               // 收到的请求 GET /configs
+	failures map[string]int         // 模拟 daemon 故障：GET 该路径时返回的状态码
+}
+
+func newFakeSwarm(t *testing.T) (*fakeSwarm, *Client) {
+	swarm := &fakeSwarm{t: t}
+	api := newTestDockerAPI(t, swarm.ServeHTTP)
+	return swarm, &Client{api: api, Service: service{api: api}, Node: node{api: api}, Event: event{api: api}, Task: task{api: api}, Config: config{api: api}, Secret: secret{api: api}, Credential: credential{api: api}}
+}
+
+// addObject 添加 config（kind=configs）或 secret（kind=secrets），content 为明文
+func (receiver *fakeSwarm) addObject(kind string, name string, content string, labels map[string]string) string {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return receiver.createObject(kind, ConfigCreateRequest{Name: name, Labels: labels, Data: base64.StdEncoding.EncodeToString([]byte(content))})
+}
+
+func (receiver *fakeSwarm) createObject(kind string, spec ConfigCreateRequest) string {
+	receiver.nextId++
+	object := &fakeSwarmObject{ID: fmt.Sprintf("%s%d", kind[:1], receiver.nextId), Spec: spec}
+	if kind == "configs" {
+		receiver.configs = append(receiver.configs, object)
+	} else {
+		receiver.secrets = append(receiver.secrets, object)
+	}
+	return object.ID
+}
+
+func (receiver *fakeSwarm) addService(spec ServiceSpec) *fakeSwarmService {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.nextId++
+	svc := &fakeSwarmService{ID: fmt.Sprintf("svc%d", receiver.nextId), Version: 1, Spec: spec}
+	receiver.services = append(receiver.services, svc)
+	return svc
+}
+
+// inspect GET /services/{id} 返回的结构
+func (receiver *fakeSwarmService) inspect() map[string]any {
+	return map[string]any{"ID": receiver.ID, "Version": map[string]int{"Index": receiver.Version}, "Spec": receiver.Spec}
+}
+
+func (receiver *fakeSwarm) service(name string) *fakeSwarmService {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	for _, svc := range receiver.services {
+		if svc.ID == name || svc.Spec.Name == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+func (receiver *fakeSwarm) objectNames(kind string) []string {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	objects := receiver.configs
+	if kind == "secrets" {
+		objects = receiver.secrets
+	}
+	var names []string
+	for _, object := range objects {
+		names = append(names, object.Spec.Name)
+	}
+	return names
+}
+
+func (receiver *fakeSwarm) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.requests = append(receiver.requests, r.Method+" "+r.URL.Path)
+	if code, ok := receiver.failures[r.URL.Path]; ok && r.Method == http.MethodGet {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"message": "daemon unavailable"})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "not found: " + r.URL.Path})
+	}
+
+	switch kind := parts[0]; kind {
+	case "configs", "secrets":
+		objects := &receiver.configs
+		if kind == "secrets" {
+			objects = &receiver.secrets
+		}
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			var filters map[string][]string
+			json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+			result := []*fakeSwarmObject{}
+			for _, object := range *objects {
+				if fakeSwarmMatch(object.Spec.Labels, filters["label"]) && (len(filters["name"]) == 0 || strings.HasPrefix(object.Spec.Name, filters["name"][0])) {
+					result = append(result, fakeSwarmSecretData(kind, object))
+				}
+			}
+			json.NewEncoder(w).Encode(result)
+		case len(parts) == 2 && parts[1] == "create":
+			var spec ConfigCreateRequest
+			json.NewDecoder(r.Body).Decode(&spec)
+			for _, object := range *objects {
+				if object.Spec.Name == spec.Name {
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]string{"message": "name conflicts with an existing object"})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"ID": receiver.createObject(kind, spec)})
+		case len(parts) == 2:
+			for i, object := range *objects {
+				if object.ID != parts[1] && object.Spec.Name != parts[1] {
+					continue
+				}
+				if r.Method == http.MethodDelete {
+					*objects = append((*objects)[:i], (*objects)[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				json.NewEncoder(w).Encode(fakeSwarmSecretData(kind, object))
+				return
+			}
+			notFound()
+		default:
+			notFound()
+		}
+	case "services":
+		switch {
+		case len(parts) == 1:
+			result := []any{}
+			for _, svc := range receiver.services {
+				result = append(result, svc.inspect())
+			}
+			json.NewEncoder(w).Encode(result)
+		case len(parts) == 2 && r.Method == http.MethodGet:
+			for _, svc := range receiver.services {
+				if svc.ID == parts[1] || svc.Spec.Name == parts[1] {
+					json.NewEncoder(w).Encode(svc.inspect())
+					return
+				}
+			}
+			notFound()
+		case len(parts) == 3 && parts[2] == "update":
+			for _, svc := range receiver.services {
+				if svc.ID != parts[1] {
+					continue
+				}
+				if r.URL.Query().Get("version") != fmt.Sprint(svc.Version) {
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(map[string]string{"message": "update out of sequence"})
+					return
+				}
+				var spec ServiceSpec
+				json.NewDecoder(r.Body).Decode(&spec)
+				svc.Spec, svc.Version = spec, svc.Version+1
+				receiver.updates = append(receiver.updates, spec.Name)
+				json.NewEncoder(w).Encode(map[string]any{})
+				return
+			}
+			notFound()
+		default:
+			notFound()
+		}
+	case "tasks":
+		result := []ServiceIdInspectJson{}
+		if receiver.tasks != nil {
+			result = receiver.tasks
+		}
+		json.NewEncoder(w).Encode(result)
+	case "nodes":
+		w.Write([]byte("[]"))
+	default:
+		notFound()
+	}
+}
+
+// fakeSwarmSecretData daemon 不会返回密钥的内容
+func fakeSwarmSecretData(kind string, object *fakeSwarmObject) *fakeSwarmObject {
+	if kind != "secrets" {
+		return object
+	}
+	copied := *object
+	copied.Spec.Data = ""
+	return &copied
+}
+
+// fakeSwarmMatch 标签过滤 key=value、key
+func fakeSwarmMatch(labels map[string]string, filters []string) bool {
+	for _, filter := range filters {
+		key, value, hasValue := strings.Cut(filter, "=")
+		actual, exists := labels[key]
+		if !exists || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestConfigPublishVersions(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	// 旧版本的配置没有 hash 标签；其它服务的配置不影响版本号
+	swarm.addObject("configs", "fops_config_v1", "a: 1", map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: "1"})
+	swarm.addObject("configs", "other_config_v7", "a: 1", map[string]string{ConfigOwnerLabel: "other", ConfigVersionLabel: "7"})
+
+	// 内容与最新版本相同（按解码后的内容比较）：跳过
+	result, err := client.Config.Publish("fops", []byte("a: 1"), nil)
+	if err != nil || !result.Skipped || result.Version != 1 || result.Name != "fops_config_v1" {
+		t.Fatalf("Publish(same content) = %#v, %v", result, err)
+	}
+
+	// 内容变化：创建 v2，带 owner、version、hash 标签
+	result, err = client.Config.Publish("fops", []byte("a: 2"), map[string]string{"env": "prod"})
+	if err != nil || result.Skipped || result.Version != 2 || result.Name != "fops_config_v2" {
+		t.Fatalf("Publish(new content) = %#v, %v", result, err)
+	}
+	latest, err := client.Config.GetVersion("fops", 2)
+	labels := latest.Spec.Labels
+	if err != nil || latest.Spec.Data != "a: 2" || labels["env"] != "prod" || labels[ConfigOwnerLabel] != "fops" || labels[ConfigVersionLabel] != "2" || labels[ConfigHashLabel] != ConfigContentHash([]byte("a: 2")) {
+		t.Fatalf("GetVersion(2) = %#v, %v", latest, err)
+	}
+
+	// 再次发布相同内容：按 hash 标签跳过
+	if result, err = client.Config.Publish("fops", []byte("a: 2"), nil); err != nil || !result.Skipped || result.Version != 2 {
+		t.Fatalf("Publish(same hash) = %#v, %v", result, err)
+	}
+	// 中间版本被清理后，版本号继续递增
+	swarm.mu.Lock()
+	swarm.configs = swarm.configs[1:]
+	swarm.mu.Unlock()
+	if result, err = client.Config.Publish("fops", []byte("a: 3"), nil); err != nil || result.Version != 3 {
+		t.Fatalf("Publish(after prune) = %#v, %v", result, err)
+	}
+}
+
+func TestUseAndRollbackConfigVersion(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	ids := map[int]string{}
+	for _, version := range []int{1, 3, 4} {
+		ids[version] = swarm.addObject("configs", ConfigVersionName("fops", version), fmt.Sprintf("v: %d", version), map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: fmt.Sprint(version)})
+	}
+	nginx := swarm.addObject("configs", "nginx_default_conf", "server {}", nil)
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Config(ServiceConfigJson{ConfigID: nginx, ConfigName: "nginx_default_conf", File: ServiceConfigFileJson{Name: "/etc/nginx.conf"}}).
+		Config(ServiceConfigJson{ConfigID: ids[4], ConfigName: "fops_config_v4", File: ServiceConfigFileJson{Name: "/app/farseer.yaml", UID: "1000", GID: "1000", Mode: 0400}}).
+		Build()
+	svc := swarm.addService(spec)
+
+	mounted := func() ServiceConfigJson {
+		for _, item := range swarm.service("fops").Spec.TaskTemplate.ContainerSpec.Configs {
+			if item.File.Name == "/app/farseer.yaml" {
+				return item
+			}
+		}
+		return ServiceConfigJson{}
+	}
+
+	// 回滚到比当前版本小的最新版本（v2 已被清理），挂载路径、权限不变，其它配置不受影响
+	version, err := client.RollbackConfig("fops")
+	if err != nil || version != 3 {
+		t.Fatalf("RollbackConfig() = %d, %v", version, err)
+	}
+	if item := mounted(); item.ConfigID != ids[3] || item.ConfigName != "fops_config_v3" || item.File.Mode != 0400 || item.File.UID != "1000" {
+		t.Fatalf("mounted config = %#v", item)
+	}
+	if configs := swarm.service("fops").Spec.TaskTemplate.ContainerSpec.Configs; len(configs) != 2 || configs[0].ConfigName != "nginx_default_conf" {
+		t.Fatalf("configs = %#v", configs)
+	}
+
+	if version, err = client.RollbackConfig("fops"); err != nil || version != 1 {
+		t.Fatalf("RollbackConfig() = %d, %v", version, err)
+	}
+	if _, err = client.RollbackConfig("fops"); err == nil {
+		t.Fatal("RollbackConfig() before v1 should fail")
+	}
+
+	// 切换到指定版本；已经是该版本时不更新服务
+	if err = client.UseConfigVersion("fops", 4); err != nil || mounted().ConfigName != "fops_config_v4" {
+		t.Fatalf("UseConfigVersion(4) = %v, %#v", err, mounted())
+	}
+	updates := svc.Version
+	if err = client.UseConfigVersion("fops", 4); err != nil || swarm.service("fops").Version != updates {
+		t.Fatalf("UseConfigVersion(current) = %v, version %d -> %d", err, updates, swarm.service("fops").Version)
+	}
+	if err = client.UseConfigVersion("fops", 2); err == nil {
+		t.Fatal("UseConfigVersion(pruned) should fail")
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -481,3 +485,76 @@ func (receiver container) ListLogFiles(containerID, dirPath string, fileExtensio
 
 	return files, nil
 }
+
+// Export 导出容器的文件系统为tar包，写入到writer
+func (receiver container) Export(containerId string, writer io.Writer) error {
+	// curl --unix-socket /var/run/docker.sock http://localhost/containers/9e76ea4b0231/export -o fops.tar
+	resp, err := receiver.api.httpClient.Get(receiver.api.URL(fmt.Sprintf("/containers/%s/export", containerId)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("export container failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+// CommitOptions 将容器提交为新镜像的参数
+type CommitOptions struct {
+	Repo    string   // 镜像仓库名称 fops
+	Tag     string   // 镜像标签 v1
+	Message string   // 提交信息
+	Author  string   // 作者
+	Changes []string // Dockerfile 指令，如：CMD ["./fops"]、ENV APP=fops
+	NoPause bool     // 提交时不暂停容器
+}
+
+// Commit 将容器提交为新的镜像，返回镜像ID
+func (receiver container) Commit(containerId string, options CommitOptions) (string, error) {
+	// curl --unix-socket /var/run/docker.sock -X POST "http://localhost/commit?container=9e76ea4b0231&repo=fops&tag=v1"
+	query := url.Values{}
+	query.Set("container", containerId)
+	query.Set("pause", strconv.FormatBool(!options.NoPause))
+	if options.Repo != "" {
+		query.Set("repo", options.Repo)
+	}
+	if options.Tag != "" {
+		query.Set("tag", options.Tag)
+	}
+	if options.Message != "" {
+		query.Set("comment", options.Message)
+	}
+	if options.Author != "" {
+		query.Set("author", options.Author)
+	}
+	for _, change := range options.Changes {
+		query.Add("changes", change)
+	}
+
+	resp, err := receiver.api.httpClient.Post(receiver.api.URL("/commit?"+query.Encode()), "application/json", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("commit container failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		ID string `json:"Id"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode commit response failed: %w", err)
+	}
+	if result.ID == "" {
+		return "", errors.New("commit container failed: daemon returned no image id")
+	}
+	return result.ID, nil
+}
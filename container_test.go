@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestContainerExport(t *testing.T) {
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/9e76ea4b0231/export" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"no such container"}`))
+			return
+		}
+		w.Write(bytes.Repeat([]byte("x"), 64*1024))
+	})
+
+	var buffer bytes.Buffer
+	if err := (container{api: api}).Export("9e76ea4b0231", &buffer); err != nil || buffer.Len() != 64*1024 {
+		t.Fatalf("Export() = %v, %d bytes", err, buffer.Len())
+	}
+	if err := (container{api: api}).Export("missing", &buffer); err == nil {
+		t.Fatal("expected error for missing container")
+	}
+}
+
+func TestContainerCommit(t *testing.T) {
+	response := `{"Id":"sha256:abc"}`
+	var query map[string][]string
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/commit" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.Query()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(response))
+	})
+	client := container{api: api}
+
+	id, err := client.Commit("9e76ea4b0231", CommitOptions{Repo: "fops", Tag: "v1", Message: "fix", Author: "farseer", Changes: []string{`CMD ["./fops"]`, "ENV APP=fops"}, NoPause: true})
+	if err != nil || id != "sha256:abc" {
+		t.Fatalf("Commit() = %s, %v", id, err)
+	}
+	want := map[string][]string{
+		"container": {"9e76ea4b0231"},
+		"pause":     {"false"},
+		"repo":      {"fops"},
+		"tag":       {"v1"},
+		"comment":   {"fix"},
+		"author":    {"farseer"},
+		"changes":   {`CMD ["./fops"]`, "ENV APP=fops"},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Fatalf("query = %v, want %v", query, want)
+	}
+
+	// 响应无法解析、没有镜像ID时返回错误
+	for _, response = range []string{`not json`, `{}`} {
+		if id, err = client.Commit("9e76ea4b0231", CommitOptions{}); err == nil {
+			t.Fatalf("Commit() with response %s = %s, want error", response, id)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	osExec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// dockerHubAuthKey Docker Hub 在 config.json 中使用的 key
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+	// credentialHelperTokenUser 凭证助手返回该用户名时，Secret 为 IdentityToken
+	credentialHelperTokenUser = "<token>"
+)
+
+// ErrCredentialNotFound 没有找到仓库的认证信息
+var ErrCredentialNotFound = errors.New("credentials not found")
+
+// AuthConfig 仓库认证信息（与 X-Registry-Auth、config.json 的字段一致）
+type AuthConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"` // base64(username:password)
+	Email         string `json:"email,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"` // 仓库地址
+	IdentityToken string `json:"identitytoken,omitempty"` // 登陆后daemon返回的token，用于换取access token
+	RegistryToken string `json:"registrytoken,omitempty"` // 直接发送给仓库的bearer token
+}
+
+// CredentialProvider 仓库认证信息的存储
+type CredentialProvider interface {
+	// Get 获取仓库的认证信息，不存在时返回 ErrCredentialNotFound
+	Get(registry string) (AuthConfig, error)
+	// Store 保存认证信息（按 ServerAddress 保存）
+	Store(auth AuthConfig) error
+	// Erase 删除仓库的认证信息
+	Erase(registry string) error
+}
+
+// EncodeAuthHeader 将认证信息编码为 X-Registry-Auth 请求头的值
+func EncodeAuthHeader(auth AuthConfig) (string, error) {
+	body, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(body), nil
+}
+
+// NormalizeRegistryHost 标准化仓库地址 https://registry.example.com/v2/ => registry.example.com，Docker Hub 统一为 docker.io
+func NormalizeRegistryHost(registry string) string {
+	registry = strings.TrimSpace(registry)
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if index := strings.Index(registry, "/"); index > -1 {
+		registry = registry[:index]
+	}
+
+	switch registry {
+	case "", defaultRegistry, legacyDefaultRegistry, "registry-1.docker.io":
+		return defaultRegistry
+	}
+	return registry
+}
+
+// credentialKey config.json 中保存的 key，Docker Hub 使用 https://index.docker.io/v1/
+func credentialKey(registry string) string {
+	if registry = NormalizeRegistryHost(registry); registry == defaultRegistry {
+		return dockerHubAuthKey
+	}
+	return registry
+}
+
+// decodeAuth 解析 auth 字段 base64(username:password)
+func (receiver *AuthConfig) decodeAuth() error {
+	if receiver.Auth == "" {
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(receiver.Auth)
+	if err != nil {
+		return fmt.Errorf("decode auth failed: %v", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return errors.New("invalid auth configuration")
+	}
+	receiver.Username, receiver.Password, receiver.Auth = username, password, ""
+	return nil
+}
+
+// DockerConfigCredentialStore 读写 docker CLI 的 config.json，支持 credsStore、credHelpers 凭证助手
+type DockerConfigCredentialStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDockerConfigCredentialStore 实例化，path 为空时使用 $DOCKER_CONFIG/config.json 或 ~/.docker/config.json
+func NewDockerConfigCredentialStore(path string) *DockerConfigCredentialStore {
+	if path == "" {
+		path = defaultDockerConfigPath()
+	}
+	return &DockerConfigCredentialStore{path: path}
+}
+
+func defaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// dockerConfigFile config.json 中与认证相关的字段
+type dockerConfigFile struct {
+	Auths       map[string]AuthConfig      `json:"auths"`
+	CredsStore  string                     `json:"credsStore"`
+	CredHelpers map[string]string          `json:"credHelpers"`
+	raw         map[string]json.RawMessage // 原始内容，写回时保留其它字段
+}
+
+// Path 配置文件路径
+func (receiver *DockerConfigCredentialStore) Path() string {
+	return receiver.path
+}
+
+func (receiver *DockerConfigCredentialStore) load() (dockerConfigFile, error) {
+	file := dockerConfigFile{Auths: map[string]AuthConfig{}, CredHelpers: map[string]string{}, raw: map[string]json.RawMessage{}}
+	data, err := os.ReadFile(receiver.path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return file, nil
+	}
+
+	if err := json.Unmarshal(data, &file.raw); err != nil {
+		return file, fmt.Errorf("parse %s failed: %v", receiver.path, err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("parse %s failed: %v", receiver.path, err)
+	}
+	if file.Auths == nil {
+		file.Auths = map[string]AuthConfig{}
+	}
+	return file, nil
+}
+
+func (receiver *DockerConfigCredentialStore) save(file dockerConfigFile) error {
+	auths, _ := json.Marshal(file.Auths)
+	file.raw["auths"] = auths
+
+	data, err := json.MarshalIndent(file.raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(receiver.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(receiver.path, data, 0600)
+}
+
+// helper 仓库使用的凭证助手，优先 credHelpers，其次 credsStore
+func (receiver dockerConfigFile) helper(registry string) string {
+	if helper, exists := receiver.CredHelpers[registry]; exists {
+		return helper
+	}
+	if helper, exists := receiver.CredHelpers[credentialKey(registry)]; exists {
+		return helper
+	}
+	return receiver.CredsStore
+}
+
+// findAuth 在 auths 中查找，兼容 https://registry.example.com 这类带协议的 key
+func (receiver dockerConfigFile) findAuth(registry string) (string, AuthConfig, bool) {
+	if auth, exists := receiver.Auths[credentialKey(registry)]; exists {
+		return credentialKey(registry), auth, true
+	}
+	for key, auth := range receiver.Auths {
+		if NormalizeRegistryHost(key) == registry {
+			return key, auth, true
+		}
+	}
+	return "", AuthConfig{}, false
+}
+
+// Get 获取仓库的认证信息
+func (receiver *DockerConfigCredentialStore) Get(registry string) (AuthConfig, error) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	registry = NormalizeRegistryHost(registry)
+	file, err := receiver.load()
+	if err != nil {
+		return AuthConfig{}, err
+	}
+
+	if helper := file.helper(registry); helper != "" {
+		return credentialHelperGet(helper, registry)
+	}
+
+	_, auth, exists := file.findAuth(registry)
+	if !exists {
+		return AuthConfig{}, ErrCredentialNotFound
+	}
+	if err := auth.decodeAuth(); err != nil {
+		return AuthConfig{}, err
+	}
+	auth.ServerAddress = registry
+	return auth, nil
+}
+
+// Store 保存认证信息，配置了凭证助手时由助手保存，否则写入 config.json 的 auths
+func (receiver *DockerConfigCredentialStore) Store(auth AuthConfig) error {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	registry := NormalizeRegistryHost(auth.ServerAddress)
+	file, err := receiver.load()
+	if err != nil {
+		return err
+	}
+
+	if helper := file.helper(registry); helper != "" {
+		if err := credentialHelperStore(helper, registry, auth); err != nil {
+			return err
+		}
+		// 与 docker CLI 一致：auths 中保留一个空的占位
+		file.Auths[credentialKey(registry)] = AuthConfig{}
+		return receiver.save(file)
+	}
+
+	stored := AuthConfig{Email: auth.Email, IdentityToken: auth.IdentityToken}
+	if auth.Username != "" || auth.Password != "" {
+		stored.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+	}
+	if key, _, exists := file.findAuth(registry); exists {
+		delete(file.Auths, key)
+	}
+	file.Auths[credentialKey(registry)] = stored
+	return receiver.save(file)
+}
+
+// Erase 删除仓库的认证信息
+func (receiver *DockerConfigCredentialStore) Erase(registry string) error {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	registry = NormalizeRegistryHost(registry)
+	file, err := receiver.load()
+	if err != nil {
+		return err
+	}
+
+	if helper := file.helper(registry); helper != "" {
+		if err := credentialHelperErase(helper, registry); err != nil {
+			return err
+		}
+	}
+
+	key, _, exists := file.findAuth(registry)
+	if !exists {
+		return nil
+	}
+	delete(file.Auths, key)
+	return receiver.save(file)
+}
+
+// credentialHelperMessage 凭证助手 stdin/stdout 的数据格式
+type credentialHelperMessage struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// runCredentialHelper 执行 docker-credential-<helper> <action>，测试时可替换
+var runCredentialHelper = func(helper string, action string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := osExec.Command("docker-credential-"+helper, action)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// 助手把错误信息写在 stdout 中
+		message := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(message, "credentials not found") {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("docker-credential-%s %s failed: %v %s", helper, action, err, message)
+	}
+	return stdout.Bytes(), nil
+}
+
+func credentialHelperGet(helper string, registry string) (AuthConfig, error) {
+	output, err := runCredentialHelper(helper, "get", []byte(credentialKey(registry)))
+	if err != nil {
+		return AuthConfig{}, err
+	}
+
+	var message credentialHelperMessage
+	if err := json.Unmarshal(output, &message); err != nil {
+		return AuthConfig{}, fmt.Errorf("docker-credential-%s get: %v", helper, err)
+	}
+
+	auth := AuthConfig{ServerAddress: registry}
+	if message.Username == credentialHelperTokenUser {
+		auth.IdentityToken = message.Secret
+	} else {
+		auth.Username, auth.Password = message.Username, message.Secret
+	}
+	return auth, nil
+}
+
+func credentialHelperStore(helper string, registry string, auth AuthConfig) error {
+	message := credentialHelperMessage{ServerURL: credentialKey(registry), Username: auth.Username, Secret: auth.Password}
+	if auth.IdentityToken != "" {
+		message.Username, message.Secret = credentialHelperTokenUser, auth.IdentityToken
+	}
+	input, _ := json.Marshal(message)
+	_, err := runCredentialHelper(helper, "store", input)
+	return err
+}
+
+func credentialHelperErase(helper string, registry string) error {
+	_, err := runCredentialHelper(helper, "erase", []byte(credentialKey(registry)))
+	if errors.Is(err, ErrCredentialNotFound) {
+		return nil
+	}
+	return err
+}
+
+// MemoryCredentialStore 保存在内存中的认证信息，用于程序中直接传入的账号密码
+type MemoryCredentialStore struct {
+	auths map[string]AuthConfig
+	mu    sync.RWMutex
+}
+
+// NewMemoryCredentialStore 实例化
+func NewMemoryCredentialStore(auths ...AuthConfig) *MemoryCredentialStore {
+	store := &MemoryCredentialStore{auths: map[string]AuthConfig{}}
+	for _, auth := range auths {
+		store.Store(auth)
+	}
+	return store
+}
+
+// Get 获取仓库的认证信息
+func (receiver *MemoryCredentialStore) Get(registry string) (AuthConfig, error) {
+	receiver.mu.RLock()
+	defer receiver.mu.RUnlock()
+
+	auth, exists := receiver.auths[NormalizeRegistryHost(registry)]
+	if !exists {
+		return AuthConfig{}, ErrCredentialNotFound
+	}
+	return auth, nil
+}
+
+// Store 保存认证信息
+func (receiver *MemoryCredentialStore) Store(auth AuthConfig) error {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	auth.ServerAddress = NormalizeRegistryHost(auth.ServerAddress)
+	if err := auth.decodeAuth(); err != nil {
+		return err
+	}
+	receiver.auths[auth.ServerAddress] = auth
+	return nil
+}
+
+// Erase 删除仓库的认证信息
+func (receiver *MemoryCredentialStore) Erase(registry string) error {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	delete(receiver.auths, NormalizeRegistryHost(registry))
+	return nil
+}
+
+// CredentialChain 按顺序从多个存储中查找认证信息，保存和删除只作用于第一个存储
+type CredentialChain []CredentialProvider
+
+// Get 获取仓库的认证信息
+func (receiver CredentialChain) Get(registry string) (AuthConfig, error) {
+	for _, provider := range receiver {
+		auth, err := provider.Get(registry)
+		if errors.Is(err, ErrCredentialNotFound) {
+			continue
+		}
+		return auth, err
+	}
+	return AuthConfig{}, ErrCredentialNotFound
+}
+
+// Store 保存认证信息
+func (receiver CredentialChain) Store(auth AuthConfig) error {
+	if len(receiver) == 0 {
+		return errors.New("no credential provider")
+	}
+	return receiver[0].Store(auth)
+}
+
+// Erase 删除仓库的认证信息
+func (receiver CredentialChain) Erase(registry string) error {
+	if len(receiver) == 0 {
+		return errors.New("no credential provider")
+	}
+	return receiver[0].Erase(registry)
+}
+
+type credential struct {
+	api *dockerAPI
+}
+
+// Provider 当前使用的认证信息存储
+func (receiver credential) Provider() CredentialProvider {
+	receiver.api.credentialsMu.RLock()
+	defer receiver.api.credentialsMu.RUnlock()
+	return receiver.api.credentials
+}
+
+// SetProvider 替换认证信息存储，例如：CredentialChain{NewMemoryCredentialStore(auth), NewDockerConfigCredentialStore("")}
+func (receiver credential) SetProvider(provider CredentialProvider) {
+	receiver.api.credentialsMu.Lock()
+	defer receiver.api.credentialsMu.Unlock()
+	receiver.api.credentials = provider
+}
+
+// Get 获取仓库的认证信息
+func (receiver credential) Get(registry string) (AuthConfig, error) {
+	return receiver.Provider().Get(registry)
+}
+
+// Store 保存认证信息
+func (receiver credential) Store(auth AuthConfig) error {
+	return receiver.Provider().Store(auth)
+}
+
+// Erase 删除仓库的认证信息
+func (receiver credential) Erase(registry string) error {
+	return receiver.Provider().Erase(registry)
+}
+
+// RegistryAuth 根据仓库地址生成 X-Registry-Auth 请求头，没有认证信息时返回空字符串
+func (receiver credential) RegistryAuth(registry string) (string, error) {
+	auth, err := receiver.Get(registry)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if auth.ServerAddress == "" {
+		auth.ServerAddress = NormalizeRegistryHost(registry)
+	}
+	return EncodeAuthHeader(auth)
+}
+
+// ImageRegistryAuth 根据镜像地址生成 X-Registry-Auth 请求头
+func (receiver credential) ImageRegistryAuth(image string) (string, error) {
+	return receiver.RegistryAuth(ImageRegistry(image))
+}
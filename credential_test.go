@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerConfigCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("farseer:hub-pwd")) + `"},
+		"https://registry.example.com": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("admin:secret")) + `"}
+	},
+	"credHelpers": {"registry.helper.com": "test"},
+	"psFormat": "table"
+}`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(run func(string, string, []byte) ([]byte, error)) { runCredentialHelper = run }(runCredentialHelper)
+	runCredentialHelper = func(helper string, action string, input []byte) ([]byte, error) {
+		if helper != "test" || action != "get" || string(input) != "registry.helper.com" {
+			t.Fatalf("unexpected helper call %s %s %s", helper, action, input)
+		}
+		return []byte(`{"ServerURL":"registry.helper.com","Username":"<token>","Secret":"identity"}`), nil
+	}
+
+	store := NewDockerConfigCredentialStore(path)
+	auth, err := store.Get("docker.io")
+	if err != nil || auth.Username != "farseer" || auth.Password != "hub-pwd" {
+		t.Fatalf("Get(docker.io) = %#v, %v", auth, err)
+	}
+
+	auth, err = store.Get("registry.example.com")
+	if err != nil || auth.Username != "admin" || auth.Password != "secret" {
+		t.Fatalf("Get(registry.example.com) = %#v, %v", auth, err)
+	}
+
+	auth, err = store.Get("registry.helper.com")
+	if err != nil || auth.IdentityToken != "identity" {
+		t.Fatalf("Get(registry.helper.com) = %#v, %v", auth, err)
+	}
+
+	if _, err = store.Get("unknown.com"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("Get(unknown.com) error = %v", err)
+	}
+
+	if err = store.Store(AuthConfig{ServerAddress: "192.168.1.2:5000", Username: "u", Password: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Erase("registry.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var raw map[string]json.RawMessage
+	json.Unmarshal(data, &raw)
+	if string(raw["psFormat"]) != `"table"` {
+		t.Fatalf("Store() lost unrelated config fields: %s", data)
+	}
+	if auth, err = store.Get("192.168.1.2:5000"); err != nil || auth.Password != "p" {
+		t.Fatalf("Get(192.168.1.2:5000) = %#v, %v", auth, err)
+	}
+	if _, err = store.Get("registry.example.com"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("Erase() did not remove credentials: %v", err)
+	}
+}
+
+func TestCredentialRegistryAuth(t *testing.T) {
+	api := &dockerAPI{}
+	receiver := credential{api: api}
+	receiver.SetProvider(CredentialChain{
+		NewMemoryCredentialStore(AuthConfig{ServerAddress: "https://registry.example.com/v2/", Username: "admin", Password: "secret"}),
+	})
+
+	header, err := receiver.ImageRegistryAuth("registry.example.com/farseer/fops:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := base64.URLEncoding.DecodeString(header)
+	var auth AuthConfig
+	json.Unmarshal(decoded, &auth)
+	if auth.Username != "admin" || auth.Password != "secret" || auth.ServerAddress != "registry.example.com" {
+		t.Fatalf("ImageRegistryAuth() = %s", decoded)
+	}
+
+	if header, err = receiver.ImageRegistryAuth("nginx"); err != nil || header != "" {
+		t.Fatalf("ImageRegistryAuth(nginx) = %q, %v", header, err)
+	}
+}
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"reflect"
 	"sync"
 
 	"github.com/farseer-go/fs/snc"
@@ -58,6 +59,21 @@ func (receiver *event) Register(handler EventHandler) {
 	receiver.handlers = append(receiver.handlers, handler)
 }
 
+// Unregister 移除事件处理器，handler 需要是可比较的类型（例如指针），通过 RegisterFunc 注册的函数无法移除
+func (receiver *event) Unregister(handler EventHandler) {
+	if handler == nil || !reflect.TypeOf(handler).Comparable() {
+		return
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	for i, h := range receiver.handlers {
+		if reflect.TypeOf(h).Comparable() && h == handler {
+			receiver.handlers = append(receiver.handlers[:i:i], receiver.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
 // RegisterFunc 注册事件处理函数（便捷方法）
 func (receiver *event) RegisterFunc(handler func(event EventResult)) {
 	receiver.Register(EventHandlerFunc(handler))
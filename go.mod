@@ -1,11 +1,13 @@
 module github.com/farseer-go/docker
 
-go 1.18.0
+// farseer-go/collections、fs、utils v0.17.3 要求 go >= 1.23.0，低于此版本时 go 命令会自动改回
+go 1.23.0
 
 require (
 	github.com/farseer-go/collections v0.17.3
 	github.com/farseer-go/fs v0.17.3
 	github.com/farseer-go/utils v0.17.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
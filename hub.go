@@ -1,6 +1,9 @@
 package docker
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/farseer-go/utils/exec"
@@ -10,27 +13,83 @@ type hub struct {
 	api *dockerAPI
 }
 
-// Login 登陆仓库(使用Docker CLI客户端)
+// Login 登陆仓库，兼容原来返回 exec.ShellWait 的用法：通过 Authenticate 验证并保存认证信息，不再依赖Docker CLI
+// 成功时 Wait() 返回 0，失败时返回 -1；需要具体的错误（如 *UnauthorizedError）时直接使用 Authenticate
 func (receiver hub) Login(dockerHub string, loginName string, loginPwd string) exec.ShellWait {
-	if loginName != "" && loginPwd != "" {
-		args := []string{"login", "-u", loginName, "--password-stdin"}
-		if loginHost := getLoginHost(dockerHub); loginHost != "" {
-			args = append(args, loginHost)
-		}
-		args = append([]string{"-u", "DOCKER_HOST", "docker"}, args...)
-		return exec.RunShellInput("env", args, nil, "", true, loginPwd)
+	result, err := receiver.Authenticate(dockerHub, loginName, loginPwd, true)
+	if err != nil {
+		return exec.NewExitShellWait(-1, err.Error())
 	}
-
-	return exec.NewExitShellWait(-1, "登陆名和密码不能为空")
+	return exec.NewExitShellWait(0, result.Status)
 }
 
 func getLoginHost(dockerHub string) string {
 	dockerHub = strings.TrimSpace(strings.TrimRight(dockerHub, "/"))
-	if dockerHub == "" || !strings.ContainsAny(dockerHub, ".:") {
+	if dockerHub == "" {
 		return ""
 	}
-	if index := strings.Index(dockerHub, "/"); index > -1 {
-		return dockerHub[:index]
+	// 只填写了仓库地址时，补一段路径再解析 registry.cn-hangzhou.aliyuncs.com => registry.cn-hangzhou.aliyuncs.com/_
+	if !strings.Contains(dockerHub, "/") {
+		dockerHub += "/_"
+	}
+	// Docker Hub 不需要指定地址
+	if registry := ImageRegistry(dockerHub); registry != defaultRegistry {
+		return registry
 	}
-	return dockerHub
+	return ""
+}
+
+// LoginResult 登陆仓库的结果
+type LoginResult struct {
+	Status        string `json:"Status"`        // Login Succeeded
+	IdentityToken string `json:"IdentityToken"` // 仓库支持时返回，用于代替密码
+	Registry      string `json:"-"`             // 登陆的仓库地址
+}
+
+// UnauthorizedError 仓库账号或密码错误
+type UnauthorizedError struct {
+	Registry string // 仓库地址
+	Message  string // daemon 返回的错误信息
+}
+
+func (receiver *UnauthorizedError) Error() string {
+	return fmt.Sprintf("login %s unauthorized: %s", receiver.Registry, receiver.Message)
+}
+
+// Authenticate 通过 daemon 验证仓库账号密码(POST /auth)，persist=true 时保存到 Client.Credential
+func (receiver hub) Authenticate(dockerHub string, loginName string, loginPwd string, persist bool) (LoginResult, error) {
+	registry := NormalizeRegistryHost(getLoginHost(dockerHub))
+	result := LoginResult{Registry: registry}
+	if loginName == "" || loginPwd == "" {
+		return result, errors.New("登陆名和密码不能为空")
+	}
+
+	// curl --unix-socket /var/run/docker.sock -X POST -d '{"username":"","password":"","serveraddress":""}' http://localhost/auth
+	auth := AuthConfig{Username: loginName, Password: loginPwd, ServerAddress: credentialKey(registry)}
+	result, err := UnixPostJsonDecode[LoginResult](receiver.api.httpClient, receiver.api.URL("/auth"), auth, nil)
+	result.Registry = registry
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return result, &UnauthorizedError{Registry: registry, Message: apiErr.Message}
+		}
+		return result, err
+	}
+
+	if persist {
+		stored := AuthConfig{ServerAddress: registry, Username: loginName, Password: loginPwd}
+		// 仓库返回了 IdentityToken 时，不保存密码
+		if result.IdentityToken != "" {
+			stored.Password, stored.IdentityToken = "", result.IdentityToken
+		}
+		if err := (credential{api: receiver.api}).Store(stored); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Logout 退出仓库，删除保存的认证信息
+func (receiver hub) Logout(dockerHub string) error {
+	return (credential{api: receiver.api}).Erase(NormalizeRegistryHost(getLoginHost(dockerHub)))
 }
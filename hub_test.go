@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHubAuthenticate(t *testing.T) {
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/auth" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var auth AuthConfig
+		json.NewDecoder(r.Body).Decode(&auth)
+		if auth.ServerAddress != "registry.example.com" {
+			t.Errorf("serveraddress = %q", auth.ServerAddress)
+		}
+		if auth.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "incorrect username or password"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Status": "Login Succeeded", "IdentityToken": "token"})
+	})
+	client := hub{api: api}
+
+	// 密码错误：映射为 *UnauthorizedError，不保存
+	_, err := client.Authenticate("registry.example.com/farseer", "admin", "wrong", true)
+	var unauthorized *UnauthorizedError
+	if !errors.As(err, &unauthorized) || unauthorized.Registry != "registry.example.com" || unauthorized.Message != "incorrect username or password" {
+		t.Fatalf("Authenticate() error = %#v", err)
+	}
+	if _, err = api.credentials.Get("registry.example.com"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("credentials stored after failed login: %v", err)
+	}
+
+	// 登陆成功：返回 IdentityToken 时只保存 token，不保存密码
+	result, err := client.Authenticate("registry.example.com/farseer", "admin", "secret", true)
+	if err != nil || result.Status != "Login Succeeded" || result.Registry != "registry.example.com" {
+		t.Fatalf("Authenticate() = %#v, %v", result, err)
+	}
+	auth, err := api.credentials.Get("registry.example.com")
+	if err != nil || auth.Username != "admin" || auth.Password != "" || auth.IdentityToken != "token" {
+		t.Fatalf("stored credentials = %#v, %v", auth, err)
+	}
+
+	if _, err = client.Authenticate("registry.example.com", "", "", false); err == nil {
+		t.Fatal("Authenticate() with empty password should fail")
+	}
+
+	// Logout 删除保存的认证信息
+	if err = client.Logout("registry.example.com/farseer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = api.credentials.Get("registry.example.com"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("credentials not erased: %v", err)
+	}
+}
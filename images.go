@@ -1,7 +1,12 @@
 package docker
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/farseer-go/utils/exec"
 )
@@ -51,3 +56,75 @@ func (receiver images) ClearImages() ([]string, error) {
 
 	return logs, nil
 }
+
+// ImageLoadProgress 导入镜像时daemon返回的进度信息
+type ImageLoadProgress struct {
+	Stream      string `json:"stream"` // 输出内容 Loaded image: nginx:latest
+	Status      string `json:"status"` // 状态
+	Progress    string `json:"progress"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// Save 导出镜像为tar包，写入到writer（支持多个镜像）
+func (receiver images) Save(writer io.Writer, imageNames ...string) error {
+	if len(imageNames) == 0 {
+		return errors.New("镜像名称不能为空")
+	}
+
+	// curl --unix-socket /var/run/docker.sock "http://localhost/images/get?names=nginx:latest&names=redis:7"
+	query := url.Values{}
+	for _, name := range imageNames {
+		query.Add("names", name)
+	}
+
+	resp, err := receiver.api.httpClient.Get(receiver.api.URL("/images/get?" + query.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("save images failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+// Load 从reader中导入镜像tar包，progress 为nil时不回调进度
+func (receiver images) Load(reader io.Reader, progress func(progress ImageLoadProgress)) error {
+	// curl --unix-socket /var/run/docker.sock -X POST -H "Content-Type: application/x-tar" --data-binary @images.tar http://localhost/images/load
+	resp, err := receiver.api.httpClient.Post(receiver.api.URL("/images/load?quiet=false"), "application/x-tar", reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("load images failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	// 响应是连续的 JSON 对象流
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var item ImageLoadProgress
+		if err := decoder.Decode(&item); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if item.Error != "" {
+			return fmt.Errorf("load images failed: %s", item.Error)
+		}
+
+		if progress != nil {
+			progress(item)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImagesSave(t *testing.T) {
+	var names []string
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/get" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		names = r.URL.Query()["names"]
+		w.Write([]byte("tar-content"))
+	})
+
+	var buffer bytes.Buffer
+	if err := (images{api: api}).Save(&buffer, "nginx:latest", "redis:7"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"nginx:latest", "redis:7"}) || buffer.String() != "tar-content" {
+		t.Fatalf("names = %v, content = %q", names, buffer.String())
+	}
+	if err := (images{api: api}).Save(&buffer); err == nil {
+		t.Fatal("expected error without image names")
+	}
+}
+
+func TestImagesLoad(t *testing.T) {
+	var uploaded string
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploaded = r.Header.Get("Content-Type") + "|" + string(body)
+		w.Write([]byte(`{"stream":"Loading layer"}` + "\n" + `{"stream":"Loaded image: nginx:latest\n"}` + "\n"))
+		if strings.Contains(string(body), "broken") {
+			w.Write([]byte(`{"error":"unexpected EOF","errorDetail":{"message":"unexpected EOF"}}`))
+		}
+	})
+
+	var streams []string
+	err := (images{api: api}).Load(strings.NewReader("tar-content"), func(progress ImageLoadProgress) {
+		streams = append(streams, progress.Stream)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded != "application/x-tar|tar-content" || len(streams) != 2 || streams[1] != "Loaded image: nginx:latest\n" {
+		t.Fatalf("uploaded = %q, streams = %q", uploaded, streams)
+	}
+
+	// 进度流中的错误
+	if err = (images{api: api}).Load(strings.NewReader("broken"), nil); err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+		t.Fatalf("Load() = %v, want stream error", err)
+	}
+}
@@ -0,0 +1,291 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeDrainOptions 排空节点的参数
+type NodeDrainOptions struct {
+	Interval time.Duration // 轮询间隔，默认 1s
+	Force    bool          // 跳过副本数检查，即使服务的副本数会低于期望也排空
+}
+
+// NodeDrainEvent 排空节点的进度
+type NodeDrainEvent struct {
+	NodeID    string   // 节点ID
+	NodeName  string   // 节点名称
+	Remaining int      // 仍在该节点运行的非全局任务数
+	Pending   []string // 运行副本数未恢复到排空前的服务：fops 1/2
+}
+
+// NodeDrainResult 排空节点的结果
+type NodeDrainResult struct {
+	NodeDrainEvent
+	Services    []string  // 排空前在该节点运行任务的服务（不含全局服务）
+	StartedAt   time.Time // 开始时间
+	CompletedAt time.Time // 完成时间
+}
+
+// NodeDrainRefusedError 排空节点后服务的副本数会低于期望，拒绝排空
+type NodeDrainRefusedError struct {
+	NodeName string   // 节点名称
+	Problems []string // 每个服务无法迁移的原因
+}
+
+func (receiver *NodeDrainRefusedError) Error() string {
+	return fmt.Sprintf("refuse to drain node %s: %s", receiver.NodeName, strings.Join(receiver.Problems, "; "))
+}
+
+// Drain 排空节点，等待非全局任务迁移到其它节点并运行（ctx 控制超时）
+// 每个服务等待运行的副本数恢复到排空前的数量（不超过期望的副本数），排空前副本数已经不足的服务不会一直等待
+// 其它节点无法满足服务的调度约束时返回 *NodeDrainRefusedError，不会修改节点；只检查调度约束和每个节点的最大副本数，不检查 CPU、内存的预留
+// nodeIdOrName 为主机名时必须只匹配一个节点
+// onProgress 在进度变化时回调，可以为 nil
+func (receiver node) Drain(ctx context.Context, nodeIdOrName string, options NodeDrainOptions, onProgress func(event NodeDrainEvent)) (NodeDrainResult, error) {
+	if options.Interval <= 0 {
+		options.Interval = defaultRolloutInterval
+	}
+	result := NodeDrainResult{StartedAt: time.Now()}
+
+	// 1. 节点、服务、节点上运行的任务
+	nodes := receiver.List()
+	target, err := findDrainTarget(nodes.ToArray(), nodeIdOrName)
+	if err != nil {
+		return result, err
+	}
+	result.NodeID, result.NodeName = target.ID, target.Description.Hostname
+
+	services, err := UnixRequestDecode[[]ServiceInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services"), nil, nil)
+	if err != nil {
+		return result, err
+	}
+	tasks, err := receiver.nodeTasks(ctx, target.ID)
+	if err != nil {
+		return result, err
+	}
+	affected := drainAffectedServices(services, tasks)
+	for _, svc := range affected {
+		result.Services = append(result.Services, svc.Spec.Name)
+	}
+
+	// 2. 检查其它节点能否承接
+	if !options.Force {
+		if problems := drainCapacityProblems(target, nodes.ToArray(), affected); len(problems) > 0 {
+			return result, &NodeDrainRefusedError{NodeName: result.NodeName, Problems: problems}
+		}
+	}
+
+	// 3. 记录排空前每个服务运行的副本数，作为迁移完成的目标
+	targets := map[string]int{}
+	for _, svc := range affected {
+		serviceTasks, err := service{api: receiver.api}.serviceTasks(ctx, svc.ID)
+		if err != nil {
+			return result, err
+		}
+		targets[svc.ID] = min(runningTasks(serviceTasks, ""), svc.Spec.Mode.Replicated.Replicas)
+	}
+
+	// 4. 排空并等待迁移完成
+	if err = receiver.UpdateContext(ctx, target.ID, func(spec *NodeSpec) error {
+		spec.Availability = NodeAvailabilityDrain
+		return nil
+	}); err != nil {
+		return result, err
+	}
+
+	lastEvent := ""
+	for {
+		if err = receiver.drainProgress(ctx, target.ID, affected, targets, &result.NodeDrainEvent); err != nil {
+			return result, err
+		}
+		if event := fmt.Sprintf("%d|%v", result.Remaining, result.Pending); event != lastEvent {
+			lastEvent = event
+			if onProgress != nil {
+				onProgress(result.NodeDrainEvent)
+			}
+		}
+		if result.Remaining == 0 && len(result.Pending) == 0 {
+			result.CompletedAt = time.Now()
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(options.Interval):
+		}
+	}
+}
+
+// findDrainTarget 按ID或主机名查找节点，ID 优先；主机名匹配多个节点时返回错误
+func findDrainTarget(nodes []DockerNodeVO, nodeIdOrName string) (DockerNodeVO, error) {
+	var matches []DockerNodeVO
+	for _, item := range nodes {
+		if item.ID == nodeIdOrName {
+			return item, nil
+		}
+		if item.Description.Hostname == nodeIdOrName {
+			matches = append(matches, item)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return DockerNodeVO{}, fmt.Errorf("no such node: %s", nodeIdOrName)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, item := range matches {
+		ids[i] = item.ID
+	}
+	return DockerNodeVO{}, fmt.Errorf("node name %s is ambiguous, use one of the node ids: %s", nodeIdOrName, strings.Join(ids, ", "))
+}
+
+// Activate 维护结束后恢复节点，重新接受调度
+func (receiver node) Activate(nodeIdOrName string) error {
+	return receiver.SetAvailability(nodeIdOrName, NodeAvailabilityActive)
+}
+
+// nodeTasks 节点上的所有任务
+func (receiver node) nodeTasks(ctx context.Context, nodeId string) ([]ServiceIdInspectJson, error) {
+	filter, _ := json.Marshal(map[string][]string{"node": {nodeId}})
+	tasksUrl := receiver.api.URL("/tasks?filters=" + url.QueryEscape(string(filter)))
+	return UnixRequestDecode[[]ServiceIdInspectJson](ctx, receiver.api.httpClient, http.MethodGet, tasksUrl, nil, nil)
+}
+
+// drainProgress 统计节点上剩余的任务，以及在其它节点运行的副本数未达到 targets 的服务
+func (receiver node) drainProgress(ctx context.Context, nodeId string, affected []ServiceInspectJson, targets map[string]int, event *NodeDrainEvent) error {
+	tasks, err := receiver.nodeTasks(ctx, nodeId)
+	if err != nil {
+		return err
+	}
+	affectedIds := map[string]bool{}
+	for _, svc := range affected {
+		affectedIds[svc.ID] = true
+	}
+	event.Remaining = 0
+	for _, item := range tasks {
+		if affectedIds[item.ServiceID] && !isTaskTerminated(item.Status.State) {
+			event.Remaining++
+		}
+	}
+
+	event.Pending = nil
+	for _, svc := range affected {
+		serviceTasks, err := service{api: receiver.api}.serviceTasks(ctx, svc.ID)
+		if err != nil {
+			return err
+		}
+		if running := runningTasks(serviceTasks, nodeId); running < targets[svc.ID] {
+			event.Pending = append(event.Pending, fmt.Sprintf("%s %d/%d", svc.Spec.Name, running, targets[svc.ID]))
+		}
+	}
+	return nil
+}
+
+// runningTasks 不在 excludeNodeId 节点上、期望运行并且运行中的任务数
+func runningTasks(tasks []ServiceIdInspectJson, excludeNodeId string) int {
+	running := 0
+	for _, item := range tasks {
+		if (excludeNodeId == "" || item.NodeID != excludeNodeId) && item.DesiredState == "running" && item.Status.State == "running" {
+			running++
+		}
+	}
+	return running
+}
+
+// isTaskTerminated 任务是否已结束
+func isTaskTerminated(state string) bool {
+	switch state {
+	case "complete", "failed", "shutdown", "rejected", "orphaned", "remove":
+		return true
+	}
+	return false
+}
+
+// drainAffectedServices 在节点上有期望运行任务的副本服务（全局服务、job 不需要迁移），按名称排序
+func drainAffectedServices(services []ServiceInspectJson, tasksOnNode []ServiceIdInspectJson) []ServiceInspectJson {
+	running := map[string]bool{}
+	for _, item := range tasksOnNode {
+		if item.DesiredState == "running" {
+			running[item.ServiceID] = true
+		}
+	}
+	var result []ServiceInspectJson
+	for _, svc := range services {
+		mode := svc.Spec.Mode
+		if running[svc.ID] && mode.Global == nil && mode.GlobalJob == nil && mode.ReplicatedJob == nil && mode.Replicated.Replicas > 0 {
+			result = append(result, svc)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Spec.Name < result[j].Spec.Name })
+	return result
+}
+
+// drainCapacityProblems 检查排空 target 后，其它可调度的节点能否运行服务期望的副本数
+// 只按调度约束和 MaxReplicas 计算，不考虑 Resources.Reservations：节点的 CPU、内存不足时任务仍可能处于 pending
+func drainCapacityProblems(target DockerNodeVO, nodes []DockerNodeVO, affected []ServiceInspectJson) []string {
+	var problems []string
+	for _, svc := range affected {
+		placement := svc.Spec.TaskTemplate.Placement
+		eligible := 0
+		for _, item := range nodes {
+			if item.ID != target.ID && strings.EqualFold(item.Spec.Availability, NodeAvailabilityActive) && strings.EqualFold(item.Status.State, "ready") && nodeMatchesConstraints(item, placement.Constraints) {
+				eligible++
+			}
+		}
+		replicas := svc.Spec.Mode.Replicated.Replicas
+		switch {
+		case eligible == 0:
+			problems = append(problems, fmt.Sprintf("service %s: no other node satisfies its placement constraints", svc.Spec.Name))
+		case placement.MaxReplicas > 0 && placement.MaxReplicas*eligible < replicas:
+			problems = append(problems, fmt.Sprintf("service %s: %d replicas exceed max %d per node on %d nodes", svc.Spec.Name, replicas, placement.MaxReplicas, eligible))
+		}
+	}
+	return problems
+}
+
+// nodeMatchesConstraints 节点是否满足调度约束 node.role==manager、node.labels.zone!=a
+// engine.labels 等无法从节点信息判断的约束视为满足
+func nodeMatchesConstraints(item DockerNodeVO, constraints []string) bool {
+	for _, constraint := range constraints {
+		equal := true
+		key, value, found := strings.Cut(constraint, "==")
+		if !found {
+			if key, value, found = strings.Cut(constraint, "!="); !found {
+				continue
+			}
+			equal = false
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var actual string
+		switch {
+		case key == "node.id":
+			actual = item.ID
+		case key == "node.hostname":
+			actual = item.Description.Hostname
+		case key == "node.role":
+			actual = item.Spec.Role
+		case key == "node.platform.os":
+			actual = item.Description.Platform.OS
+		case key == "node.platform.arch":
+			actual = item.Description.Platform.Architecture
+		case strings.HasPrefix(key, "node.labels."):
+			actual = item.Spec.Labels[strings.TrimPrefix(key, "node.labels.")]
+		default:
+			continue
+		}
+		if strings.EqualFold(actual, value) != equal {
+			return false
+		}
+	}
+	return true
+}
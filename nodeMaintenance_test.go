@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrainCapacityProblems(t *testing.T) {
+	newNode := func(id, hostname, role string, labels map[string]string) DockerNodeVO {
+		var item DockerNodeVO
+		item.ID, item.Description.Hostname = id, hostname
+		item.Spec.Role, item.Spec.Availability, item.Spec.Labels = role, NodeAvailabilityActive, labels
+		item.Status.State = "ready"
+		return item
+	}
+	nodes := []DockerNodeVO{
+		newNode("n1", "node-1", NodeRoleManager, map[string]string{"zone": "a"}),
+		newNode("n2", "node-2", NodeRoleWorker, map[string]string{"zone": "b"}),
+		newNode("n3", "node-3", NodeRoleWorker, map[string]string{"zone": "b"}),
+	}
+	newService := func(name string, replicas int, placement Placement) ServiceInspectJson {
+		var svc ServiceInspectJson
+		svc.ID, svc.Spec.Name = name, name
+		svc.Spec.Mode.Replicated.Replicas = replicas
+		svc.Spec.TaskTemplate.Placement = placement
+		return svc
+	}
+	affected := []ServiceInspectJson{
+		newService("fops", 2, Placement{}),
+		newService("manager", 1, Placement{Constraints: []string{"node.role==manager"}}),
+		newService("zone", 3, Placement{Constraints: []string{"node.labels.zone!=a"}, MaxReplicas: 1}),
+		newService("spread", 4, Placement{MaxReplicas: 2}),
+	}
+
+	problems := drainCapacityProblems(nodes[0], nodes, affected)
+	want := []string{
+		"service manager: no other node satisfies its placement constraints",
+		"service zone: 3 replicas exceed max 1 per node on 2 nodes",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("got %v, want %v", problems, want)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Fatalf("got %q, want %q", problems[i], want[i])
+		}
+	}
+}
+
+func TestFindDrainTarget(t *testing.T) {
+	newNode := func(id, hostname string) DockerNodeVO {
+		var item DockerNodeVO
+		item.ID, item.Description.Hostname = id, hostname
+		return item
+	}
+	nodes := []DockerNodeVO{newNode("n1", "node-1"), newNode("n2", "node-2"), newNode("n3", "node-2"), newNode("node-3", "node-4")}
+
+	for name, want := range map[string]string{"n1": "n1", "node-1": "n1", "n3": "n3", "node-3": "node-3"} {
+		if target, err := findDrainTarget(nodes, name); err != nil || target.ID != want {
+			t.Fatalf("findDrainTarget(%s) = %s, %v; want %s", name, target.ID, err, want)
+		}
+	}
+	if _, err := findDrainTarget(nodes, "node-2"); err == nil || !strings.Contains(err.Error(), "n2, n3") {
+		t.Fatalf("expected ambiguous node error, got %v", err)
+	}
+	if _, err := findDrainTarget(nodes, "missing"); err == nil {
+		t.Fatal("expected no such node error")
+	}
+}
+
+func TestDrainUnderReplicatedService(t *testing.T) {
+	var mu sync.Mutex
+	drained := false
+	newTask := func(id, nodeId, desired, state string) ServiceIdInspectJson {
+		var item ServiceIdInspectJson
+		item.ID, item.ServiceID, item.NodeID, item.DesiredState, item.Status.State = id, "s1", nodeId, desired, state
+		return item
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// fops 期望 3 个副本，排空前只运行了 2 个（第 3 个副本一直无法调度）
+		tasks := []ServiceIdInspectJson{newTask("t1", "n1", "running", "running"), newTask("t2", "n2", "running", "running"), newTask("t3", "", "running", "pending")}
+		if drained {
+			tasks[0] = newTask("t1", "n1", "shutdown", "shutdown")
+			tasks = append(tasks, newTask("t4", "n2", "running", "running"))
+		}
+		switch {
+		case r.URL.Path == "/nodes":
+			var nodes []DockerNodeVO
+			for _, id := range []string{"n1", "n2"} {
+				var item DockerNodeVO
+				item.ID, item.Description.Hostname = id, "node-"+id[1:]
+				item.Spec.Role, item.Spec.Availability, item.Status.State = NodeRoleWorker, NodeAvailabilityActive, "ready"
+				nodes = append(nodes, item)
+			}
+			json.NewEncoder(w).Encode(nodes)
+		case r.URL.Path == "/services":
+			var svc ServiceInspectJson
+			svc.ID, svc.Spec.Name, svc.Spec.Mode.Replicated.Replicas = "s1", "fops", 3
+			json.NewEncoder(w).Encode([]ServiceInspectJson{svc})
+		case r.URL.Path == "/tasks":
+			var filters map[string]json.RawMessage
+			json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+			var result []ServiceIdInspectJson
+			for _, item := range tasks {
+				if _, byNode := filters["node"]; !byNode || item.NodeID == "n1" {
+					result = append(result, item)
+				}
+			}
+			json.NewEncoder(w).Encode(result)
+		case r.URL.Path == "/nodes/n1" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{"ID": "n1", "Version": map[string]int{"Index": 1}, "Spec": NodeSpec{Role: NodeRoleWorker, Availability: NodeAvailabilityActive}})
+		case r.URL.Path == "/nodes/n1/update":
+			drained = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+	client := node{api: newTestDockerAPI(t, handler)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.Drain(ctx, "node-1", NodeDrainOptions{Interval: 10 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 以排空前运行的 2 个副本为目标，不等待一直无法调度的第 3 个副本
+	if result.NodeID != "n1" || result.Remaining != 0 || len(result.Pending) != 0 || len(result.Services) != 1 || result.CompletedAt.IsZero() {
+		t.Fatalf("result = %+v", result)
+	}
+}
@@ -0,0 +1,152 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 节点状态
+const (
+	NodeAvailabilityActive = "active" // 可以调度任务
+	NodeAvailabilityPause  = "pause"  // 不再调度新任务，已有任务继续运行
+	NodeAvailabilityDrain  = "drain"  // 不再调度新任务，已有任务迁移到其它节点
+)
+
+// 节点角色
+const (
+	NodeRoleManager = "manager"
+	NodeRoleWorker  = "worker"
+)
+
+// NodeSpec 节点的可修改配置
+type NodeSpec struct {
+	Name         string            `json:"Name,omitempty"`
+	Labels       map[string]string `json:"Labels"`       // 标签
+	Role         string            `json:"Role"`         // 节点角色   manager worker
+	Availability string            `json:"Availability"` // 节点状态   active pause drain
+}
+
+// nodeInspectJson 更新节点时需要的版本号和配置
+type nodeInspectJson struct {
+	ID      string `json:"ID"`
+	Version struct {
+		Index int `json:"Index"`
+	} `json:"Version"`
+	Spec NodeSpec `json:"Spec"`
+}
+
+// Update 读取节点当前配置，通过 mutate 修改后提交，版本冲突时自动重新读取并重试
+func (receiver node) Update(nodeIdOrName string, mutate func(spec *NodeSpec) error) error {
+	return receiver.UpdateContext(context.Background(), nodeIdOrName, mutate)
+}
+
+// UpdateContext 同 Update（支持 ctx 控制超时/取消）
+func (receiver node) UpdateContext(ctx context.Context, nodeIdOrName string, mutate func(spec *NodeSpec) error) error {
+	for attempt := 0; ; attempt++ {
+		// curl --unix-socket /var/run/docker.sock http://localhost/nodes/node-1
+		current, err := UnixRequestDecode[nodeInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/nodes/%s", nodeIdOrName)), nil, nil)
+		if err != nil {
+			return err
+		}
+		if current.ID == "" {
+			return fmt.Errorf("no such node: %s", nodeIdOrName)
+		}
+
+		spec := current.Spec
+		if spec.Labels == nil {
+			spec.Labels = map[string]string{}
+		}
+		if err = mutate(&spec); err != nil {
+			return err
+		}
+		if err = validateNodeSpec(current.Spec, spec); err != nil {
+			return err
+		}
+
+		// curl --unix-socket /var/run/docker.sock -X POST -d '{Spec}' http://localhost/nodes/xxx/update?version=123
+		updateUrl := receiver.api.URL(fmt.Sprintf("/nodes/%s/update?version=%d", current.ID, current.Version.Index))
+		_, err = UnixRequestDecode[struct{}](ctx, receiver.api.httpClient, http.MethodPost, updateUrl, spec, nil)
+		if err == nil || !IsUpdateOutOfSequence(err) || attempt >= serviceUpdateMaxRetries {
+			return err
+		}
+
+		// 版本冲突：等待后重新读取最新配置再提交
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * serviceUpdateRetryDelay):
+		}
+	}
+}
+
+// SetAvailability 设置节点状态 active pause drain
+func (receiver node) SetAvailability(nodeIdOrName string, availability string) error {
+	return receiver.Update(nodeIdOrName, func(spec *NodeSpec) error {
+		spec.Availability = availability
+		return nil
+	})
+}
+
+// AddLabels 添加节点标签，已存在的标签会被覆盖
+func (receiver node) AddLabels(nodeIdOrName string, labels map[string]string) error {
+	return receiver.Update(nodeIdOrName, func(spec *NodeSpec) error {
+		for key, value := range labels {
+			spec.Labels[key] = value
+		}
+		return nil
+	})
+}
+
+// RemoveLabels 移除节点标签，不存在的标签忽略
+func (receiver node) RemoveLabels(nodeIdOrName string, keys ...string) error {
+	return receiver.Update(nodeIdOrName, func(spec *NodeSpec) error {
+		for _, key := range keys {
+			delete(spec.Labels, key)
+		}
+		return nil
+	})
+}
+
+// Promote 将节点提升为管理节点
+func (receiver node) Promote(nodeIdOrName string) error {
+	return receiver.Update(nodeIdOrName, func(spec *NodeSpec) error {
+		spec.Role = NodeRoleManager
+		return nil
+	})
+}
+
+// Demote 将管理节点降级为工作节点
+func (receiver node) Demote(nodeIdOrName string) error {
+	return receiver.Update(nodeIdOrName, func(spec *NodeSpec) error {
+		spec.Role = NodeRoleWorker
+		return nil
+	})
+}
+
+// Remove 从集群中移除节点，force=true 时可以移除未下线（仍在运行）的节点
+func (receiver node) Remove(nodeIdOrName string, force bool) error {
+	// curl --unix-socket /var/run/docker.sock -X DELETE http://localhost/nodes/node-1?force=true
+	_, err := UnixDelete(receiver.api.httpClient, receiver.api.URL(fmt.Sprintf("/nodes/%s?force=%t", nodeIdOrName, force)))
+	return err
+}
+
+// validateNodeSpec 只校验 mutate 修改过的字段，当前值（例如新版本 daemon 增加的状态）原样提交
+func validateNodeSpec(current NodeSpec, spec NodeSpec) error {
+	if spec.Availability != current.Availability {
+		switch spec.Availability {
+		case NodeAvailabilityActive, NodeAvailabilityPause, NodeAvailabilityDrain:
+		default:
+			return fmt.Errorf("invalid node availability %q, expected active, pause or drain", spec.Availability)
+		}
+	}
+	if spec.Role != current.Role {
+		switch spec.Role {
+		case NodeRoleManager, NodeRoleWorker:
+		default:
+			return fmt.Errorf("invalid node role %q, expected manager or worker", spec.Role)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNodeDaemon 模拟 GET /nodes/{id}、POST /nodes/{id}/update，conflicts 为返回版本冲突的次数
+type fakeNodeDaemon struct {
+	mu        sync.Mutex
+	node      nodeInspectJson
+	conflicts int
+	updates   []string // 提交时使用的版本号
+}
+
+func (receiver *fakeNodeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && (r.URL.Path == "/nodes/"+receiver.node.ID || r.URL.Path == "/nodes/"+receiver.node.Spec.Name):
+		json.NewEncoder(w).Encode(receiver.node)
+	case r.Method == http.MethodPost && r.URL.Path == "/nodes/"+receiver.node.ID+"/update":
+		receiver.updates = append(receiver.updates, r.URL.Query().Get("version"))
+		if receiver.conflicts > 0 {
+			// 模拟其它请求在读取后修改了节点
+			receiver.conflicts--
+			receiver.node.Version.Index++
+			receiver.node.Spec.Labels["other"] = "1"
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"rpc error: code = Unknown desc = update out of sequence"}`))
+			return
+		}
+		if r.URL.Query().Get("version") != fmt.Sprint(receiver.node.Version.Index) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var spec NodeSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		receiver.node.Spec = spec
+		receiver.node.Version.Index++
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"node not found"}`))
+	}
+}
+
+func newFakeNodeDaemon(t *testing.T, spec NodeSpec) (*fakeNodeDaemon, node) {
+	daemon := &fakeNodeDaemon{}
+	daemon.node.ID, daemon.node.Version.Index, daemon.node.Spec = "n1", 10, spec
+	return daemon, node{api: newTestDockerAPI(t, daemon.ServeHTTP)}
+}
+
+func TestNodeUpdateRetriesOnConflict(t *testing.T) {
+	daemon, client := newFakeNodeDaemon(t, NodeSpec{Name: "node-1", Labels: map[string]string{"zone": "a"}, Role: NodeRoleWorker, Availability: NodeAvailabilityActive})
+	daemon.conflicts = 1
+
+	if err := client.AddLabels("node-1", map[string]string{"disk": "ssd"}); err != nil {
+		t.Fatal(err)
+	}
+	// 冲突后重新读取最新版本并提交，保留其它请求的修改
+	if strings.Join(daemon.updates, ",") != "10,11" {
+		t.Fatalf("update versions = %v, want [10 11]", daemon.updates)
+	}
+	labels := daemon.node.Spec.Labels
+	if len(labels) != 3 || labels["disk"] != "ssd" || labels["other"] != "1" || labels["zone"] != "a" {
+		t.Fatalf("labels = %v", labels)
+	}
+
+	if err := client.RemoveLabels("n1", "zone", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := daemon.node.Spec.Labels["zone"]; exists || len(daemon.node.Spec.Labels) != 2 {
+		t.Fatalf("labels = %v", daemon.node.Spec.Labels)
+	}
+}
+
+func TestNodeUpdateValidatesChangedFields(t *testing.T) {
+	// 当前状态为未知值（新版本 daemon），修改标签时不校验
+	daemon, client := newFakeNodeDaemon(t, NodeSpec{Role: NodeRoleWorker, Availability: "maintenance"})
+	if err := client.AddLabels("n1", map[string]string{"disk": "ssd"}); err != nil {
+		t.Fatal(err)
+	}
+	if daemon.node.Spec.Availability != "maintenance" || daemon.node.Spec.Labels["disk"] != "ssd" {
+		t.Fatalf("spec = %+v", daemon.node.Spec)
+	}
+
+	// 修改为无效的值时不提交
+	updates := len(daemon.updates)
+	if err := client.SetAvailability("n1", "paused"); err == nil {
+		t.Fatal("expected invalid availability error")
+	}
+	if err := client.Update("n1", func(spec *NodeSpec) error { spec.Role = "leader"; return nil }); err == nil {
+		t.Fatal("expected invalid role error")
+	}
+	if len(daemon.updates) != updates {
+		t.Fatalf("invalid spec should not be submitted: %v", daemon.updates)
+	}
+
+	if err := client.SetAvailability("n1", NodeAvailabilityDrain); err != nil || daemon.node.Spec.Availability != NodeAvailabilityDrain {
+		t.Fatalf("SetAvailability() = %v, %+v", err, daemon.node.Spec)
+	}
+	if err := client.Promote("missing"); err == nil {
+		t.Fatal("expected no such node error")
+	}
+}
+
+func TestValidateNodeSpec(t *testing.T) {
+	current := NodeSpec{Role: NodeRoleWorker, Availability: NodeAvailabilityActive}
+	tests := []struct {
+		name    string
+		current NodeSpec
+		spec    NodeSpec
+		wantErr bool
+	}{
+		{"unchanged", current, current, false},
+		{"pause", current, NodeSpec{Role: NodeRoleWorker, Availability: NodeAvailabilityPause}, false},
+		{"promote", current, NodeSpec{Role: NodeRoleManager, Availability: NodeAvailabilityActive}, false},
+		{"invalid availability", current, NodeSpec{Role: NodeRoleWorker, Availability: "paused"}, true},
+		{"empty availability", current, NodeSpec{Role: NodeRoleWorker}, true},
+		{"invalid role", current, NodeSpec{Role: "leader", Availability: NodeAvailabilityActive}, true},
+		{"unknown current values", NodeSpec{Role: "observer", Availability: "maintenance"}, NodeSpec{Role: "observer", Availability: "maintenance", Labels: map[string]string{"a": "b"}}, false},
+	}
+	for _, test := range tests {
+		if err := validateNodeSpec(test.current, test.spec); (err != nil) != test.wantErr {
+			t.Errorf("%s: validateNodeSpec() = %v, wantErr %v", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultRegistry       = "docker.io" // 默认仓库
+	defaultRepoNamespace  = "library"   // 官方镜像的命名空间
+	defaultImageTag       = "latest"    // 默认标签
+	legacyDefaultRegistry = "index.docker.io"
+	maxImageNameLength    = 255
+)
+
+var (
+	// 仓库地址：域名（或IP）+ 可选端口，也支持 [IPv6]:端口
+	registryRegexp = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?$`)
+	// 仓库路径中的每一段：小写字母和数字，可用 . _ __ - 分隔
+	pathComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*$`)
+	tagRegexp           = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp        = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+)
+
+// ImageReference 镜像地址解析结果
+// 例如：nginx:1.25 解析为 docker.io / library/nginx / 1.25
+type ImageReference struct {
+	Registry   string // 仓库地址 docker.io、registry.cn-hangzhou.aliyuncs.com、192.168.1.2:5000
+	Repository string // 仓库路径 library/nginx、farseer/fops
+	Tag        string // 标签，未指定标签和摘要时为 latest
+	Digest     string // 摘要 sha256:...
+}
+
+// ParseImageReference 解析并标准化镜像地址
+func ParseImageReference(image string) (ImageReference, error) {
+	var ref ImageReference
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return ref, errors.New("image reference is empty")
+	}
+
+	// 1. 摘要部分 name@sha256:...
+	name := image
+	if index := strings.Index(name, "@"); index > -1 {
+		ref.Digest = name[index+1:]
+		name = name[:index]
+		if !digestRegexp.MatchString(ref.Digest) {
+			return ref, fmt.Errorf("invalid digest %q in image reference %q", ref.Digest, image)
+		}
+	}
+
+	// 2. 标签部分：最后一个冒号必须在最后一个斜杠之后，否则是仓库端口
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		ref.Tag = name[index+1:]
+		name = name[:index]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("invalid tag %q in image reference %q", ref.Tag, image)
+		}
+	}
+
+	// 3. 仓库地址和路径
+	ref.Registry, ref.Repository = splitImageRegistry(name)
+	if !registryRegexp.MatchString(ref.Registry) {
+		return ref, fmt.Errorf("invalid registry %q in image reference %q", ref.Registry, image)
+	}
+	if ref.Repository == "" {
+		return ref, fmt.Errorf("invalid image reference %q: repository is empty", image)
+	}
+	for _, component := range strings.Split(ref.Repository, "/") {
+		if !pathComponentRegexp.MatchString(component) {
+			return ref, fmt.Errorf("invalid repository %q in image reference %q", ref.Repository, image)
+		}
+	}
+	if len(ref.Registry)+1+len(ref.Repository) > maxImageNameLength {
+		return ref, fmt.Errorf("invalid image reference %q: name longer than %d characters", image, maxImageNameLength)
+	}
+
+	// 4. 未指定标签和摘要时，使用 latest
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultImageTag
+	}
+	return ref, nil
+}
+
+// splitImageRegistry 拆分仓库地址和仓库路径（不含标签和摘要）
+func splitImageRegistry(name string) (string, string) {
+	registry, remainder := defaultRegistry, name
+	if index := strings.Index(name, "/"); index > -1 {
+		first := name[:index]
+		// 第一段包含 . 或 : 或者是 localhost 时，才认为是仓库地址
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry, remainder = first, name[index+1:]
+		}
+	}
+
+	if registry == legacyDefaultRegistry {
+		registry = defaultRegistry
+	}
+	// 官方镜像补齐 library 命名空间
+	if registry == defaultRegistry && remainder != "" && !strings.Contains(remainder, "/") {
+		remainder = defaultRepoNamespace + "/" + remainder
+	}
+	return registry, remainder
+}
+
+// Name 完整的镜像名称（不含标签和摘要） docker.io/library/nginx
+func (receiver ImageReference) Name() string {
+	return receiver.Registry + "/" + receiver.Repository
+}
+
+// FamiliarName 简短的镜像名称，与 docker CLI 显示一致 nginx、farseer/fops、192.168.1.2:5000/fops
+func (receiver ImageReference) FamiliarName() string {
+	if receiver.Registry != defaultRegistry {
+		return receiver.Name()
+	}
+	return strings.TrimPrefix(receiver.Repository, defaultRepoNamespace+"/")
+}
+
+// String 完整的镜像地址 docker.io/library/nginx:1.25@sha256:...
+func (receiver ImageReference) String() string {
+	return receiver.format(receiver.Name())
+}
+
+// FamiliarString 简短的镜像地址 nginx:1.25@sha256:...
+func (receiver ImageReference) FamiliarString() string {
+	return receiver.format(receiver.FamiliarName())
+}
+
+func (receiver ImageReference) format(name string) string {
+	if receiver.Tag != "" {
+		name += ":" + receiver.Tag
+	}
+	if receiver.Digest != "" {
+		name += "@" + receiver.Digest
+	}
+	return name
+}
+
+// WithoutDigest 去掉摘要部分
+func (receiver ImageReference) WithoutDigest() ImageReference {
+	receiver.Digest = ""
+	return receiver
+}
+
+// Equal 两个镜像地址标准化后是否一致（nginx 与 docker.io/library/nginx:latest 视为相同）
+func (receiver ImageReference) Equal(other ImageReference) bool {
+	return receiver == other
+}
+
+// SameRepository 是否同一个仓库（忽略标签和摘要）
+func (receiver ImageReference) SameRepository(other ImageReference) bool {
+	return receiver.Registry == other.Registry && receiver.Repository == other.Repository
+}
+
+// TrimImageDigest 去掉镜像地址中的摘要部分，其余部分保持调用方的原样 nginx:1.25@sha256:... => nginx:1.25
+// 不补全 tag、仓库：nginx@sha256:... => nginx，docker.io/library/nginx:1.25@sha256:... => docker.io/library/nginx:1.25
+func TrimImageDigest(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	return name
+}
+
+// ImageRegistry 获取镜像所在的仓库地址，Docker Hub 的镜像返回 docker.io
+func ImageRegistry(image string) string {
+	registry, _ := splitImageRegistry(strings.Split(strings.TrimSpace(image), "@")[0])
+	return registry
+}
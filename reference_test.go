@@ -0,0 +1,120 @@
+package docker
+
+import "testing"
+
+func TestParseImageReference(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		image    string
+		want     ImageReference
+		familiar string
+	}{
+		{
+			name:     "official image without tag",
+			image:    "nginx",
+			want:     ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"},
+			familiar: "nginx:latest",
+		},
+		{
+			name:     "docker hub user image",
+			image:    "farseer/fops:v1",
+			want:     ImageReference{Registry: "docker.io", Repository: "farseer/fops", Tag: "v1"},
+			familiar: "farseer/fops:v1",
+		},
+		{
+			name:     "legacy docker hub registry",
+			image:    "index.docker.io/library/redis:7",
+			want:     ImageReference{Registry: "docker.io", Repository: "library/redis", Tag: "7"},
+			familiar: "redis:7",
+		},
+		{
+			name:     "private registry with port",
+			image:    "192.168.1.2:5000/fops:v2",
+			want:     ImageReference{Registry: "192.168.1.2:5000", Repository: "fops", Tag: "v2"},
+			familiar: "192.168.1.2:5000/fops:v2",
+		},
+		{
+			name:     "localhost registry",
+			image:    "localhost/fops",
+			want:     ImageReference{Registry: "localhost", Repository: "fops", Tag: "latest"},
+			familiar: "localhost/fops:latest",
+		},
+		{
+			name:     "tag and digest",
+			image:    "registry.cn-hangzhou.aliyuncs.com/farseer/fops:v3@" + digest,
+			want:     ImageReference{Registry: "registry.cn-hangzhou.aliyuncs.com", Repository: "farseer/fops", Tag: "v3", Digest: digest},
+			familiar: "registry.cn-hangzhou.aliyuncs.com/farseer/fops:v3@" + digest,
+		},
+		{
+			name:     "digest only",
+			image:    "nginx@" + digest,
+			want:     ImageReference{Registry: "docker.io", Repository: "library/nginx", Digest: digest},
+			familiar: "nginx@" + digest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImageReference(tt.image)
+			if err != nil {
+				t.Fatalf("ParseImageReference() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseImageReference() = %#v, want %#v", got, tt.want)
+			}
+			if got.FamiliarString() != tt.familiar {
+				t.Fatalf("FamiliarString() = %q, want %q", got.FamiliarString(), tt.familiar)
+			}
+		})
+	}
+}
+
+func TestParseImageReferenceInvalid(t *testing.T) {
+	for _, image := range []string{"", "Nginx", "nginx:", "nginx@sha256:abc", "registry.io/", "my_registry.io:5000/fops"} {
+		if _, err := ParseImageReference(image); err == nil {
+			t.Fatalf("ParseImageReference(%q) expected error", image)
+		}
+	}
+}
+
+func TestTrimImageDigest(t *testing.T) {
+	digest := "@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := map[string]string{
+		"":                                      "",
+		"nginx":                                 "nginx",
+		"nginx" + digest:                        "nginx",
+		"farseer/fops:v1" + digest:              "farseer/fops:v1",
+		"docker.io/library/nginx:1.25" + digest: "docker.io/library/nginx:1.25",
+		"localhost:5000/fops:v1" + digest:       "localhost:5000/fops:v1",
+	}
+	for image, want := range tests {
+		if got := TrimImageDigest(image); got != want {
+			t.Fatalf("TrimImageDigest(%q) = %q, want %q", image, got, want)
+		}
+	}
+
+	a, _ := ParseImageReference("nginx")
+	b, _ := ParseImageReference("docker.io/library/nginx:latest")
+	if !a.Equal(b) {
+		t.Fatalf("Equal() = false, want true")
+	}
+}
+
+func TestGetLoginHost(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"farseer":   "",
+		"docker.io": "",
+		"registry.cn-hangzhou.aliyuncs.com/farseer": "registry.cn-hangzhou.aliyuncs.com",
+		"192.168.1.2:5000/":                         "192.168.1.2:5000",
+		"localhost/fops":                            "localhost",
+	}
+
+	for dockerHub, want := range tests {
+		if got := getLoginHost(dockerHub); got != want {
+			t.Fatalf("getLoginHost(%q) = %q, want %q", dockerHub, got, want)
+		}
+	}
+}
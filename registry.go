@@ -0,0 +1,470 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farseer-go/collections"
+)
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	dockerHubRegistryHost       = "registry-1.docker.io" // Docker Hub 的 v2 接口地址
+)
+
+// manifestAccept 请求 manifest 时接受的类型（同时支持多架构）
+var manifestAccept = []string{MediaTypeDockerManifestList, MediaTypeOCIIndex, MediaTypeDockerManifest, MediaTypeOCIManifest}
+
+// registry 直接访问镜像仓库的 Registry HTTP API v2（不经过 docker daemon）
+type registry struct {
+	api   *dockerAPI
+	state *registryState
+}
+
+type registryState struct {
+	httpClient *http.Client
+	insecure   map[string]bool   // 使用 http 访问的仓库
+	tokens     map[string]string // 缓存的 bearer token，key: 仓库地址 + scope
+	mu         sync.RWMutex
+}
+
+func newRegistry(api *dockerAPI) registry {
+	return registry{api: api, state: &registryState{
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+		insecure:   map[string]bool{},
+		tokens:     map[string]string{},
+	}}
+}
+
+// RegistryManifest 镜像的 manifest（单架构）或 manifest list（多架构）
+type RegistryManifest struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	MediaType     string `json:"mediaType"`
+	Digest        string `json:"-"` // Docker-Content-Digest
+	Config        struct {
+		MediaType string `json:"mediaType"`
+		Size      int64  `json:"size"`
+		Digest    string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+		Size      int64  `json:"size"`
+		Digest    string `json:"digest"`
+	} `json:"layers"`
+	Manifests []RegistryPlatformManifest `json:"manifests"` // 多架构时，每个平台的 manifest
+}
+
+// RegistryPlatformManifest manifest list 中单个平台的 manifest
+type RegistryPlatformManifest struct {
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Digest    string `json:"digest"`
+	Platform  struct {
+		Architecture string `json:"architecture"` // amd64 arm64
+		OS           string `json:"os"`           // linux
+		Variant      string `json:"variant"`      // v8
+	} `json:"platform"`
+}
+
+// IsList 是否为多架构的 manifest list
+func (receiver RegistryManifest) IsList() bool {
+	return receiver.MediaType == MediaTypeDockerManifestList || receiver.MediaType == MediaTypeOCIIndex || len(receiver.Manifests) > 0
+}
+
+// SetInsecure 指定使用 http 访问的仓库（localhost、127.0.0.1 默认使用 http）
+func (receiver registry) SetInsecure(registryHost string) {
+	receiver.state.mu.Lock()
+	defer receiver.state.mu.Unlock()
+	receiver.state.insecure[NormalizeRegistryHost(registryHost)] = true
+}
+
+// Tags 获取镜像的所有标签（自动翻页）
+func (receiver registry) Tags(image string) (collections.List[string], error) {
+	lst := collections.NewList[string]()
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return lst, err
+	}
+
+	// GET /v2/<name>/tags/list?n=100，下一页通过 Link: </v2/<name>/tags/list?last=xx&n=100>; rel="next" 返回
+	next := receiver.baseURL(ref.Registry) + fmt.Sprintf("/v2/%s/tags/list?n=100", ref.Repository)
+	for next != "" {
+		resp, err := receiver.do(ref, http.MethodGet, next, nil, "pull")
+		if err != nil {
+			return lst, err
+		}
+
+		var result struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return lst, err
+		}
+		lst.Add(result.Tags...)
+
+		next = receiver.nextLink(next, resp.Header.Get("Link"))
+	}
+	return lst, nil
+}
+
+// ManifestDigest 获取标签当前指向的 digest（多架构镜像返回 manifest list 的 digest，与 swarm 固定的 digest 一致）
+func (receiver registry) ManifestDigest(image string) (string, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := receiver.do(ref, http.MethodHead, receiver.manifestURL(ref), manifestAccept, "pull")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		// 部分仓库 HEAD 不返回 digest，改为读取 manifest
+		manifest, err := receiver.Manifest(image)
+		return manifest.Digest, err
+	}
+	return digest, nil
+}
+
+// Manifest 获取镜像的 manifest
+func (receiver registry) Manifest(image string) (RegistryManifest, error) {
+	var manifest RegistryManifest
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return manifest, err
+	}
+
+	resp, err := receiver.do(ref, http.MethodGet, receiver.manifestURL(ref), manifestAccept, "pull")
+	if err != nil {
+		return manifest, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return manifest, err
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = strings.Split(resp.Header.Get("Content-Type"), ";")[0]
+	}
+	manifest.Digest = resp.Header.Get("Docker-Content-Digest")
+	return manifest, nil
+}
+
+// IsLatest 镜像地址中的 digest 是否与仓库中标签当前指向的 digest 一致
+// 例如服务正在运行的 farseer/fops:v1@sha256:xxx
+func (receiver registry) IsLatest(image string) (bool, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return false, err
+	}
+	if ref.Digest == "" {
+		return false, fmt.Errorf("image %q has no digest", image)
+	}
+
+	digest, err := receiver.ManifestDigest(ref.WithoutDigest().String())
+	return digest == ref.Digest, err
+}
+
+// DeleteTag 删除标签（删除标签指向的 manifest，仓库需开启删除功能）
+func (receiver registry) DeleteTag(image string) error {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return err
+	}
+
+	// 仓库只支持按 digest 删除
+	if ref.Digest == "" {
+		if ref.Digest, err = receiver.ManifestDigest(image); err != nil {
+			return err
+		}
+	}
+
+	resp, err := receiver.do(ref, http.MethodDelete, receiver.baseURL(ref.Registry)+fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, ref.Digest), nil, "pull,push,delete")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (receiver registry) manifestURL(ref ImageReference) string {
+	reference := ref.Tag
+	if ref.Digest != "" {
+		reference = ref.Digest
+	}
+	return receiver.baseURL(ref.Registry) + fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, reference)
+}
+
+// baseURL 仓库的访问地址，Docker Hub 使用 registry-1.docker.io
+func (receiver registry) baseURL(registryHost string) string {
+	if registryHost == defaultRegistry {
+		return "https://" + dockerHubRegistryHost
+	}
+
+	receiver.state.mu.RLock()
+	insecure := receiver.state.insecure[registryHost]
+	receiver.state.mu.RUnlock()
+
+	host := registryHost
+	if h, _, err := net.SplitHostPort(registryHost); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); insecure || host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "http://" + registryHost
+	}
+	return "https://" + registryHost
+}
+
+// nextLink 解析分页的 Link 头
+func (receiver registry) nextLink(current string, link string) string {
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	currentURL, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	nextURL, err := currentURL.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return nextURL.String()
+}
+
+// do 发送请求，遇到 401 时按 WWW-Authenticate 完成 Basic 或 Bearer token 认证后重试
+func (receiver registry) do(ref ImageReference, method string, requestURL string, accept []string, actions string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:%s", ref.Repository, actions)
+	tokenKey := ref.Registry + "|" + scope
+
+	receiver.state.mu.RLock()
+	token := receiver.state.tokens[tokenKey]
+	receiver.state.mu.RUnlock()
+
+	send := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequest(method, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, mediaType := range accept {
+			req.Header.Add("Accept", mediaType)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return receiver.state.httpClient.Do(req)
+	}
+
+	authorization := ""
+	if token != "" {
+		authorization = "Bearer " + token
+	}
+	resp, err := send(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		auth, err := receiver.credentials(ref.Registry)
+		if err != nil {
+			return nil, err
+		}
+
+		scheme, params := parseAuthChallenge(challenge)
+		switch scheme {
+		case "basic":
+			authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+		case "bearer":
+			if auth.RegistryToken != "" {
+				token = auth.RegistryToken
+			} else if token, err = receiver.fetchToken(params, scope, auth); err != nil {
+				return nil, err
+			}
+			receiver.state.mu.Lock()
+			receiver.state.tokens[tokenKey] = token
+			receiver.state.mu.Unlock()
+			authorization = "Bearer " + token
+		default:
+			return nil, fmt.Errorf("unsupported registry auth challenge %q", challenge)
+		}
+
+		if resp, err = send(authorization); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newRegistryError(resp)
+	}
+	return resp, nil
+}
+
+// credentials 复用 Client.Credential 中的认证信息，没有时匿名访问
+func (receiver registry) credentials(registryHost string) (AuthConfig, error) {
+	auth, err := (credential{api: receiver.api}).Get(registryHost)
+	if errors.Is(err, ErrCredentialNotFound) {
+		return AuthConfig{}, nil
+	}
+	return auth, err
+}
+
+// fetchToken 向认证服务获取 bearer token
+func (receiver registry) fetchToken(params map[string]string, scope string, auth AuthConfig) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.New("registry auth challenge has no realm")
+	}
+	if params["scope"] != "" {
+		scope = params["scope"]
+	}
+
+	var req *http.Request
+	var err error
+	if auth.IdentityToken != "" {
+		// 使用 IdentityToken 时，通过 OAuth2 refresh_token 换取 token
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", auth.IdentityToken)
+		form.Set("service", params["service"])
+		form.Set("scope", scope)
+		form.Set("client_id", "farseer-go")
+		req, err = http.NewRequest(http.MethodPost, realm, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := url.Values{}
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		query.Set("scope", scope)
+		separator := "?"
+		if strings.Contains(realm, "?") {
+			separator = "&"
+		}
+		req, err = http.NewRequest(http.MethodGet, realm+separator+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		if auth.Username != "" {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+	}
+
+	resp, err := receiver.state.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newRegistryError(resp)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.Token == "" {
+		result.Token = result.AccessToken
+	}
+	if result.Token == "" {
+		return "", errors.New("registry auth server returned an empty token")
+	}
+	return result.Token, nil
+}
+
+// parseAuthChallenge 解析 WWW-Authenticate: Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(value)
+		}
+		rest = strings.TrimLeft(strings.TrimSpace(rest), ",")
+	}
+	return strings.ToLower(scheme), params
+}
+
+// RegistryError 镜像仓库返回的错误
+type RegistryError struct {
+	StatusCode int    // HTTP 状态码
+	Code       string // MANIFEST_UNKNOWN、NAME_UNKNOWN、UNAUTHORIZED、UNSUPPORTED
+	Message    string
+}
+
+func (receiver *RegistryError) Error() string {
+	if receiver.Code != "" {
+		return fmt.Sprintf("registry request failed (%d) %s: %s", receiver.StatusCode, receiver.Code, receiver.Message)
+	}
+	return fmt.Sprintf("registry request failed (%d): %s", receiver.StatusCode, receiver.Message)
+}
+
+// newRegistryError 解析 {"errors":[{"code":"","message":""}]}
+func newRegistryError(resp *http.Response) *RegistryError {
+	result := &RegistryError{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(resp.Body)
+
+	var errs struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &errs) == nil && len(errs.Errors) > 0 {
+		result.Code, result.Message = errs.Errors[0].Code, errs.Errors[0].Message
+	} else {
+		result.Message = strings.TrimSpace(string(body))
+	}
+	if result.Message == "" {
+		result.Message = http.StatusText(resp.StatusCode)
+	}
+	return result
+}
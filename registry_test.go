@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry 模拟需要 bearer token 认证的仓库
+func newTestRegistry(t *testing.T) *httptest.Server {
+	const listDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	deleted := false
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" || r.URL.Query().Get("service") != "test-registry" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "token-" + r.URL.Query().Get("scope")})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v2/")
+		for _, suffix := range []string{"/tags/", "/manifests/"} {
+			if index := strings.Index(name, suffix); index > -1 {
+				name = name[:index]
+			}
+		}
+		scope := "repository:" + name + ":pull"
+		if r.Method == http.MethodDelete {
+			scope += ",push,delete"
+		}
+		if r.Header.Get("Authorization") != "Bearer token-"+scope {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"code": "UNAUTHORIZED", "message": "authentication required"}}})
+			return
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tags/list"):
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/`+name+`/tags/list?last=v2&n=2>; rel="next"`)
+				json.NewEncoder(w).Encode(map[string]any{"name": name, "tags": []string{"v1", "v2"}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"name": name, "tags": []string{"v3"}})
+		case strings.HasSuffix(r.URL.Path, "/manifests/v3"):
+			if deleted {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", MediaTypeDockerManifestList)
+			w.Header().Set("Docker-Content-Digest", listDigest)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`{"schemaVersion":2,"mediaType":"` + MediaTypeDockerManifestList + `","manifests":[{"digest":"sha256:2222","platform":{"architecture":"amd64","os":"linux"}},{"digest":"sha256:3333","platform":{"architecture":"arm64","os":"linux","variant":"v8"}}]}`))
+			}
+		case strings.HasSuffix(r.URL.Path, "/manifests/"+listDigest) && r.Method == http.MethodDelete:
+			deleted = true
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}}})
+		}
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRegistry(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	api := &dockerAPI{}
+	(credential{api: api}).SetProvider(NewMemoryCredentialStore(AuthConfig{ServerAddress: host, Username: "admin", Password: "secret"}))
+	receiver := newRegistry(api)
+	image := host + "/farseer/fops"
+
+	tags, err := receiver.Tags(image)
+	if err != nil || strings.Join(tags.ToArray(), ",") != "v1,v2,v3" {
+		t.Fatalf("Tags() = %v, %v", tags.ToArray(), err)
+	}
+
+	digest, err := receiver.ManifestDigest(image + ":v3")
+	if err != nil || digest != "sha256:1111111111111111111111111111111111111111111111111111111111111111" {
+		t.Fatalf("ManifestDigest() = %q, %v", digest, err)
+	}
+
+	latest, err := receiver.IsLatest(image + ":v3@" + digest)
+	if err != nil || !latest {
+		t.Fatalf("IsLatest() = %v, %v", latest, err)
+	}
+
+	manifest, err := receiver.Manifest(image + ":v3")
+	if err != nil || !manifest.IsList() || len(manifest.Manifests) != 2 || manifest.Manifests[1].Platform.Variant != "v8" {
+		t.Fatalf("Manifest() = %#v, %v", manifest, err)
+	}
+
+	if err = receiver.DeleteTag(image + ":v3"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	if _, err = receiver.ManifestDigest(image + ":v3"); err == nil {
+		t.Fatal("ManifestDigest() expected error after DeleteTag()")
+	}
+}
+
+func TestRegistryUnauthorized(t *testing.T) {
+	server := newTestRegistry(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	api := &dockerAPI{}
+	(credential{api: api}).SetProvider(NewMemoryCredentialStore())
+	_, err := newRegistry(api).Tags(host + "/farseer/fops")
+	if registryErr, ok := err.(*RegistryError); !ok || registryErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Tags() error = %v", err)
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Fatalf("parseAuthChallenge() = %q, %#v", scheme, params)
+	}
+}
@@ -0,0 +1,186 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/collections"
+	"github.com/farseer-go/fs/parse"
+)
+
+type secret struct {
+	api *dockerAPI
+}
+
+// SecretDriver 外部密钥驱动（为空时由 swarm 内置存储）
+type SecretDriver struct {
+	Name    string            `json:"Name"`
+	Options map[string]string `json:"Options,omitempty"`
+}
+
+type SecretSpec struct {
+	Name       string            `json:"Name"`
+	Labels     map[string]string `json:"Labels"`
+	Data       string            `json:"Data,omitempty"` // 注意：发送给 API 时必须是 Base64 编码的字符串，读取时不会返回
+	Driver     *SecretDriver     `json:"Driver,omitempty"`
+	Templating *ConfigTemplating `json:"Templating,omitempty"` // 模板驱动，为空时内容原样挂载
+}
+
+type SecretInfo struct {
+	ID        string     `json:"ID"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	Spec      SecretSpec `json:"Spec"`
+	Version   int        `json:"-"` // 版本号,读取Spec.Labels["version"]
+}
+
+// SecretListOptions 查询密钥的过滤条件，为空的条件不过滤
+type SecretListOptions struct {
+	ID     string            // 密钥ID（前缀匹配）
+	Name   string            // 密钥名称（前缀匹配）
+	Labels map[string]string // 密钥标签，value 为空时只要求存在该标签
+}
+
+// SecretPublishResult 发布密钥的结果
+type SecretPublishResult struct {
+	ID      string // 密钥ID
+	Name    string // 密钥名称 fops_secret_v3
+	Version int    // 版本号
+}
+
+// SecretVersionName 版本化密钥的名称：fops_secret_v3
+func SecretVersionName(serviceName string, version int) string {
+	return fmt.Sprintf("%s_secret_v%d", serviceName, version)
+}
+
+// Create 创建密钥，driver 为空时使用 swarm 内置存储
+func (receiver secret) Create(name string, content []byte, labels map[string]string, driver *SecretDriver) (string, error) {
+	// curl --unix-socket /var/run/docker.sock -X POST -d '{"Name":"fops_secret_v1","Data":"base64"}' http://localhost/secrets/create
+	data := SecretSpec{
+		Name:   name,
+		Labels: labels,
+		Data:   base64.StdEncoding.EncodeToString(content), // 必须 Base64
+		Driver: driver,
+	}
+	result, err := UnixPostJsonDecode[struct{ ID string }](receiver.api.httpClient, receiver.api.URL("/secrets/create"), data, nil)
+	if err != nil {
+		return "", fmt.Errorf("create secret %s failed: %w", name, err)
+	}
+	return result.ID, nil
+}
+
+// Inspect 查看密钥的元数据 (通过 ID 或 Name)，docker 不会返回密钥内容
+func (receiver secret) Inspect(secretIdOrName string) (SecretInfo, error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/secrets/fops_secret_v1
+	result, err := UnixRequestDecode[SecretInfo](context.Background(), receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/secrets/%s", secretIdOrName)), nil, nil)
+	if err != nil {
+		return result, err
+	}
+	if result.ID == "" {
+		return result, fmt.Errorf("no such secret: %s", secretIdOrName)
+	}
+	result.Version = parse.ToInt(result.Spec.Labels[ConfigVersionLabel])
+	return result, nil
+}
+
+// List 查询密钥列表
+func (receiver secret) List(options SecretListOptions) (collections.List[SecretInfo], error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/secrets?filters={"label":["owner_service=fops"]}
+	secretsUrl := receiver.api.URL("/secrets") + listFilters(options.ID, options.Name, options.Labels)
+	secrets, err := UnixRequestDecode[collections.List[SecretInfo]](context.Background(), receiver.api.httpClient, http.MethodGet, secretsUrl, nil, nil)
+	if err != nil {
+		return collections.NewList[SecretInfo](), err
+	}
+	secrets.Foreach(func(item *SecretInfo) {
+		item.Version = parse.ToInt(item.Spec.Labels[ConfigVersionLabel])
+	})
+	return secrets, nil
+}
+
+// Remove 删除密钥（被服务使用中的密钥无法删除）
+func (receiver secret) Remove(secretIdOrName string) error {
+	// curl --unix-socket /var/run/docker.sock -X DELETE http://localhost/secrets/fops_secret_v1
+	_, err := UnixDelete(receiver.api.httpClient, receiver.api.URL(fmt.Sprintf("/secrets/%s", secretIdOrName)))
+	return err
+}
+
+// Versions 服务的所有版本化密钥，按版本号倒序
+func (receiver secret) Versions(serviceName string) (collections.List[SecretInfo], error) {
+	secrets, err := receiver.List(SecretListOptions{Labels: map[string]string{ConfigOwnerLabel: serviceName}})
+	if err != nil {
+		return secrets, err
+	}
+	return secrets.OrderByDescending(func(item SecretInfo) any {
+		return item.Version
+	}).ToList(), nil
+}
+
+// Publish 发布服务的新版本密钥 <service>_secret_v<N>
+// 与配置不同，密钥的内容无法读取，也不在标签中保存内容的 hash，所以每次都会创建新版本
+func (receiver secret) Publish(serviceName string, content []byte, labels map[string]string) (SecretPublishResult, error) {
+	versions, err := receiver.Versions(serviceName)
+	if err != nil {
+		return SecretPublishResult{}, err
+	}
+	version := versions.First().Version + 1
+
+	secretLabels := map[string]string{}
+	for key, value := range labels {
+		secretLabels[key] = value
+	}
+	secretLabels[ConfigOwnerLabel] = serviceName
+	secretLabels[ConfigVersionLabel] = strconv.Itoa(version)
+
+	name := SecretVersionName(serviceName, version)
+	id, err := receiver.Create(name, content, secretLabels, nil)
+	if err != nil {
+		return SecretPublishResult{}, err
+	}
+	return SecretPublishResult{ID: id, Name: name, Version: version}, nil
+}
+
+// RotateSecret 发布服务的新版本密钥，并替换服务挂载的所有版本化密钥（<service>_secret_vN），挂载路径、权限保持不变
+func (receiver *Client) RotateSecret(serviceName string, content []byte, labels map[string]string) (SecretPublishResult, error) {
+	svc, err := receiver.Service.Inspect(serviceName)
+	if err != nil {
+		return SecretPublishResult{}, err
+	}
+	prefix := serviceName + "_secret_v"
+	if !isVersionedSecretMounted(svc.Spec.TaskTemplate.ContainerSpec.Secrets, prefix) {
+		return SecretPublishResult{}, fmt.Errorf("service %s has no versioned secret mounted", serviceName)
+	}
+
+	result, err := receiver.Secret.Publish(serviceName, content, labels)
+	if err != nil {
+		return result, err
+	}
+	// 同一个版本化密钥可能挂载到多个路径，一次更新全部替换，只触发一次滚动更新
+	_, err = receiver.Service.Update(serviceName, func(spec *ServiceSpec) error {
+		secrets := spec.TaskTemplate.ContainerSpec.Secrets
+		if !isVersionedSecretMounted(secrets, prefix) {
+			return fmt.Errorf("service %s has no versioned secret mounted", serviceName)
+		}
+		for i := range secrets {
+			if strings.HasPrefix(secrets[i].SecretName, prefix) {
+				secrets[i].SecretID, secrets[i].SecretName = result.ID, result.Name
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// isVersionedSecretMounted 是否挂载了名称以 prefix 开头的密钥
+func isVersionedSecretMounted(secrets []ServiceSecretJson, prefix string) bool {
+	for _, item := range secrets {
+		if strings.HasPrefix(item.SecretName, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSecretPublishVersions(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	swarm.addObject("secrets", "fops_secret_v2", "old", map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: "2"})
+	swarm.addObject("secrets", "other_secret_v9", "old", map[string]string{ConfigOwnerLabel: "other", ConfigVersionLabel: "9"})
+
+	// 密钥的内容无法读取，相同的内容也会创建新版本
+	for _, version := range []int{3, 4} {
+		result, err := client.Secret.Publish("fops", []byte("old"), map[string]string{"env": "prod"})
+		if err != nil || result.Version != version || result.Name != SecretVersionName("fops", version) || result.ID == "" {
+			t.Fatalf("Publish() = %#v, %v; want version %d", result, err, version)
+		}
+	}
+	latest, err := client.Secret.Inspect("fops_secret_v4")
+	if err != nil || latest.Version != 4 || latest.Spec.Labels[ConfigOwnerLabel] != "fops" || latest.Spec.Labels["env"] != "prod" {
+		t.Fatalf("Inspect() = %#v, %v", latest, err)
+	}
+
+	var apiErr *APIError
+	if _, err = client.Secret.Inspect("fops_secret_v9"); !errors.As(err, &apiErr) || !apiErr.IsNotFound() {
+		t.Fatalf("Inspect() of a missing secret = %v, want a not found error", err)
+	}
+	// 查询失败时不能当作没有历史版本，从 v1 重新发布
+	swarm.failures = map[string]int{"/secrets": http.StatusInternalServerError}
+	if result, err := client.Secret.Publish("fops", []byte("new"), nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Publish() = %#v, %v; want the daemon error", result, err)
+	}
+	if names := swarm.objectNames("secrets"); len(names) != 4 {
+		t.Fatalf("no secret should be created, got %v", names)
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	v1 := swarm.addObject("secrets", "fops_secret_v1", "pwd1", map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: "1"})
+	tls := swarm.addObject("secrets", "tls_key", "key", nil)
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Secret(ServiceSecretJson{SecretID: v1, SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "db_password", UID: "1000", GID: "1000", Mode: 0400}}).
+		Secret(ServiceSecretJson{SecretID: tls, SecretName: "tls_key", File: ServiceConfigFileJson{Name: "tls.key", UID: "0", GID: "0", Mode: 0400}}).
+		Secret(ServiceSecretJson{SecretID: v1, SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "/app/db_password", UID: "0", GID: "0", Mode: 0444}}).
+		Build()
+	swarm.addService(spec)
+
+	result, err := client.RotateSecret("fops", []byte("pwd2"), nil)
+	if err != nil || result.Version != 2 {
+		t.Fatalf("RotateSecret() = %#v, %v", result, err)
+	}
+	// 所有挂载的版本化密钥都替换为新版本（一次更新），挂载路径、权限不变，其它密钥不受影响
+	if len(swarm.updates) != 1 {
+		t.Fatalf("updates = %v, want 1", swarm.updates)
+	}
+	secrets := swarm.service("fops").Spec.TaskTemplate.ContainerSpec.Secrets
+	want := []ServiceSecretJson{
+		{SecretID: result.ID, SecretName: "fops_secret_v2", File: ServiceConfigFileJson{Name: "db_password", UID: "1000", GID: "1000", Mode: 0400}},
+		{SecretID: tls, SecretName: "tls_key", File: ServiceConfigFileJson{Name: "tls.key", UID: "0", GID: "0", Mode: 0400}},
+		{SecretID: result.ID, SecretName: "fops_secret_v2", File: ServiceConfigFileJson{Name: "/app/db_password", UID: "0", GID: "0", Mode: 0444}},
+	}
+	if len(secrets) != len(want) {
+		t.Fatalf("secrets = %#v", secrets)
+	}
+	for i := range want {
+		if secrets[i] != want[i] {
+			t.Fatalf("secrets[%d] = %#v, want %#v", i, secrets[i], want[i])
+		}
+	}
+
+	// 没有挂载版本化密钥时不发布新版本
+	spec, _ = NewServiceSpec("web", "nginx").Build()
+	swarm.addService(spec)
+	if _, err = client.RotateSecret("web", []byte("pwd"), nil); err == nil {
+		t.Fatal("RotateSecret() without versioned secret should fail")
+	}
+	if names := swarm.objectNames("secrets"); len(names) != 3 {
+		t.Fatalf("secrets = %v", names)
+	}
+}
+
+func TestDetachSecretByTarget(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Secret(ServiceSecretJson{SecretID: "s1", SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "db_password"}}).
+		Secret(ServiceSecretJson{SecretID: "s1", SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "/app/db_password"}}).
+		Build()
+	swarm.addService(spec)
+
+	// 与 AttachSecret 一致，按挂载路径匹配：同一个密钥的其它挂载保留
+	if err := client.Service.DetachSecret("fops", "/app/db_password"); err != nil {
+		t.Fatal(err)
+	}
+	secrets := swarm.service("fops").Spec.TaskTemplate.ContainerSpec.Secrets
+	if len(secrets) != 1 || secrets[0].File.Name != "db_password" {
+		t.Fatalf("secrets = %#v", secrets)
+	}
+
+	// 按密钥名称不再匹配
+	err := client.Service.DetachSecret("fops", "fops_secret_v1")
+	if !errors.Is(err, ErrFileNotMounted) {
+		t.Fatalf("DetachSecret(secret name) = %v, want ErrFileNotMounted", err)
+	}
+}
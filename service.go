@@ -1,13 +1,12 @@
 package docker
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"time"
 
 	"fmt"
@@ -39,8 +38,8 @@ func (receiver service) Inspect(serviceName string) (ServiceInspectJson, error)
 		return result, errors.New("no such service")
 	}
 
-	result.Spec.TaskTemplate.ContainerSpec.Image = strings.Split(result.Spec.TaskTemplate.ContainerSpec.Image, "@")[0]                 // 去掉 digest 部分
-	result.PreviousSpec.TaskTemplate.ContainerSpec.Image = strings.Split(result.PreviousSpec.TaskTemplate.ContainerSpec.Image, "@")[0] // 去掉 digest 部分
+	result.Spec.TaskTemplate.ContainerSpec.Image = TrimImageDigest(result.Spec.TaskTemplate.ContainerSpec.Image)                 // 去掉 digest 部分
+	result.PreviousSpec.TaskTemplate.ContainerSpec.Image = TrimImageDigest(result.PreviousSpec.TaskTemplate.ContainerSpec.Image) // 去掉 digest 部分
 	return result, nil
 }
 
@@ -65,62 +64,88 @@ func (receiver service) Delete(serviceName string) error {
 }
 
 // SetImagesAndReplicas 更新镜像版本和副本数量
+// 与 SetImages 相同：镜像固定到 digest，daemon 接受更新后立即返回，不等待滚动更新完成
 func (receiver service) SetImagesAndReplicas(serviceName string, dockerImages string, dockerReplicas int) exec.ShellWait {
-	args := []string{
-		"service", "update",
-		"--image", dockerImages,
-		"--replicas", fmt.Sprintf("%d", dockerReplicas),
-		"--with-registry-auth",
-		"--update-order", "start-first",
-		serviceName,
-	}
-	return exec.RunShell("docker", args, receiver.api.cliEnv(nil), "", false)
+	image, warning := receiver.pinImageDigest(context.Background(), dockerImages)
+	result, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		if err := setServiceReplicas(spec, dockerReplicas); err != nil {
+			return err
+		}
+		setServiceImage(spec, image, 0)
+		return nil
+	})
+	return toShellWait(serviceName, withWarning(result, warning), err)
 }
 
 // SetImages 更新镜像版本
+// 镜像会固定到仓库中当前的 digest（与 docker service update --image 一致），仓库无法访问时使用原镜像并在输出中给出警告
+// 注意：CLI 时期会等待滚动更新收敛后才退出，现在 daemon 接受更新后立即返回，Wait() 的退出码只表示提交是否成功
+// 需要等待更新结果时使用 RolloutImage 或 WatchRollout
 func (receiver service) SetImages(serviceName string, dockerImages string, updateDelay int) exec.ShellWait {
 	return receiver.SetImagesContext(context.Background(), serviceName, dockerImages, updateDelay)
 }
 
-// SetImagesContext 更新镜像版本（支持 ctx 控制超时/取消）
+// SetImagesContext 更新镜像版本（支持 ctx 控制超时/取消），说明见 SetImages
 func (receiver service) SetImagesContext(ctx context.Context, serviceName string, dockerImages string, updateDelay int) exec.ShellWait {
-	args := []string{
-		"service", "update",
-		"--image", dockerImages,
-		"--with-registry-auth",
-		"--update-order", "start-first",
+	image, warning := receiver.pinImageDigest(ctx, dockerImages)
+	result, err := receiver.UpdateContext(ctx, serviceName, func(spec *ServiceSpec) error {
+		setServiceImage(spec, image, updateDelay)
+		return nil
+	})
+	return toShellWait(serviceName, withWarning(result, warning), err)
+}
+
+// SetReplicas 更新副本数量
+func (receiver service) SetReplicas(serviceName string, dockerReplicas int) exec.ShellWait {
+	result, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		return setServiceReplicas(spec, dockerReplicas)
+	})
+	return toShellWait(serviceName, result, err)
+}
+
+// Restart 重启容器
+func (receiver service) Restart(serviceName string) exec.ShellWait {
+	result, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		// ForceUpdate 变化时，即使配置没有变化也会重建所有任务
+		spec.TaskTemplate.ForceUpdate++
+		return nil
+	})
+	return toShellWait(serviceName, result, err)
+}
+
+// setServiceImage 更新镜像，使用 start-first 滚动更新（对应 --image --update-order start-first --update-delay）
+func setServiceImage(spec *ServiceSpec, dockerImages string, updateDelay int) {
+	spec.TaskTemplate.ContainerSpec.Image = dockerImages
+	if spec.UpdateConfig == nil {
+		spec.UpdateConfig = &UpdateConfig{Parallelism: 1, FailureAction: "pause"}
 	}
+	spec.UpdateConfig.Order = "start-first"
 
 	// 滚动更新时的时间间隔
 	if updateDelay > 0 {
-		args = append(args, fmt.Sprintf("--update-delay=%ds", updateDelay))
+		spec.UpdateConfig.Delay = int64(time.Duration(updateDelay) * time.Second)
 	}
-
-	args = append(args, serviceName)
-
-	return exec.RunShellContext(ctx, "docker", args, receiver.api.cliEnv(nil), "", true)
 }
 
-// SetReplicas 更新副本数量
-func (receiver service) SetReplicas(serviceName string, dockerReplicas int) exec.ShellWait {
-	args := []string{
-		"service", "update",
-		"--replicas", fmt.Sprintf("%d", dockerReplicas),
-		"--with-registry-auth",
-		serviceName,
+// setServiceReplicas 更新副本数量（对应 --replicas）
+func setServiceReplicas(spec *ServiceSpec, dockerReplicas int) error {
+	if spec.Mode.IsGlobal() || spec.Mode.IsJob() {
+		return fmt.Errorf("service %s is not a replicated service", spec.Name)
 	}
-	return exec.RunShell("docker", args, receiver.api.cliEnv(nil), "", false)
+	spec.Mode.Replicated.Replicas = dockerReplicas
+	return nil
 }
 
-// Restart 重启容器
-func (receiver service) Restart(serviceName string) exec.ShellWait {
-	args := []string{
-		"service", "update",
-		"--with-registry-auth",
-		"--force",
-		serviceName,
+// toShellWait 将更新结果转换为 ShellWait，保持与 CLI 时期的返回值兼容
+func toShellWait(serviceName string, result ServiceUpdateResult, err error) exec.ShellWait {
+	if err != nil {
+		return exec.NewExitShellWait(-1, err.Error())
+	}
+	message := serviceName
+	for _, warning := range result.Warnings {
+		message += "\n" + warning
 	}
-	return exec.RunShell("docker", args, receiver.api.cliEnv(nil), "", false)
+	return exec.NewExitShellWait(0, message)
 }
 
 type ConfigTarget struct {
@@ -173,6 +198,75 @@ func (receiver service) Create(serviceName, dockerNodeRole, additionalScripts, d
 	return exec.RunShell("docker", args, receiver.api.cliEnv(nil), "", true)
 }
 
+// ServiceCreateResult 创建服务的结果
+type ServiceCreateResult struct {
+	ID       string   `json:"ID"`       // 服务ID
+	Warnings []string `json:"Warnings"` // 警告信息，如：镜像无法解析digest
+}
+
+// CreateSpec 根据 ServiceSpec 创建服务（POST /services/create，不依赖CLI），自动携带镜像仓库的认证信息
+func (receiver service) CreateSpec(spec ServiceSpec) (ServiceCreateResult, error) {
+	headers, err := receiver.registryAuthHeaders(spec.TaskTemplate.ContainerSpec.Image)
+	if err != nil {
+		return ServiceCreateResult{}, err
+	}
+
+	// curl --unix-socket /var/run/docker.sock -X POST -H "Content-Type: application/json" -d '{...}' http://localhost/services/create
+	return UnixPostJsonDecode[ServiceCreateResult](receiver.api.httpClient, receiver.api.URL("/services/create"), spec, headers)
+}
+
+// registryAuthHeaders 根据镜像生成 X-Registry-Auth 请求头
+func (receiver service) registryAuthHeaders(image string) (map[string]string, error) {
+	registryAuth, err := (credential{api: receiver.api}).ImageRegistryAuth(image)
+	if err != nil || registryAuth == "" {
+		return nil, err
+	}
+	return map[string]string{"X-Registry-Auth": registryAuth}, nil
+}
+
+// resolveImageDigest 通过 daemon 查询镜像在仓库中的 digest，返回固定到 digest 的镜像 nginx:1.25@sha256:...
+// 固定 digest 后所有节点运行同一个镜像，即使 tag 被重新推送也不会混用
+func (receiver service) resolveImageDigest(ctx context.Context, image string) (string, error) {
+	if strings.Contains(image, "@") {
+		return image, nil
+	}
+	headers, err := receiver.registryAuthHeaders(image)
+	if err != nil {
+		return image, err
+	}
+
+	// curl --unix-socket /var/run/docker.sock -H "X-Registry-Auth: xxx" http://localhost/distribution/nginx:1.25/json
+	distribution, err := UnixRequestDecode[struct {
+		Descriptor struct {
+			Digest string `json:"digest"`
+		} `json:"Descriptor"`
+	}](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/distribution/%s/json", image)), nil, headers)
+	if err != nil {
+		return image, err
+	}
+	if distribution.Descriptor.Digest == "" {
+		return image, fmt.Errorf("no digest returned for image %s", image)
+	}
+	return image + "@" + distribution.Descriptor.Digest, nil
+}
+
+// pinImageDigest 固定镜像的 digest，仓库无法访问时使用原镜像并返回警告（与 CLI 的行为一致）
+func (receiver service) pinImageDigest(ctx context.Context, image string) (string, string) {
+	pinned, err := receiver.resolveImageDigest(ctx, image)
+	if err != nil {
+		return image, fmt.Sprintf("image %s could not be accessed on a registry to record its digest: %v", image, err)
+	}
+	return pinned, ""
+}
+
+// withWarning 将本地产生的警告追加到更新结果
+func withWarning(result ServiceUpdateResult, warning string) ServiceUpdateResult {
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	return result
+}
+
 // ParseShellArgs 解析 shell 风格的参数字符串（支持引号和续行符）
 func ParseShellArgs(s string) []string {
 	// 处理续行符：\ + 换行符 -> 空格
@@ -214,64 +308,24 @@ func ParseShellArgs(s string) []string {
 	return args
 }
 
-// Logs 获取日志
+// Logs 获取日志，按任务分组，与 docker service logs --tail 一致：tailCount=0 时不读取历史日志，<0 时读取全部
 func (receiver service) Logs(serviceIdOrServiceName string, tailCount int) (collections.List[ServiceLogVO], error) {
-	args := []string{
-		"service", "logs",
-		serviceIdOrServiceName,
-		"--tail", fmt.Sprintf("%d", tailCount),
-	}
-
-	wait := exec.RunShell("docker", args, receiver.api.cliEnv(nil), "", true)
-	lstLog, exitCode := wait.WaitToList()
-
-	lst := collections.NewList[ServiceLogVO]()
-	if exitCode != 0 {
-		return lst, fmt.Errorf("获取日志失败。")
+	tail := strconv.Itoa(tailCount)
+	if tailCount < 0 {
+		tail = ServiceLogTailAll
 	}
-
-	lstLog.Foreach(func(item *string) {
-		// fops.1.l71hvj98bsqx@master    | 2026-03-04 17:57:02.672616 Initialization completed, total time: 344 ms
-		logs := strings.SplitN(*item, "|", 2)
-		if len(logs) != 2 {
-			return
-		}
-
-		// 得到容器名称和节点名称 fops.1.l71hvj98bsqx@master
-		name_Id_NodeName := strings.TrimSpace(logs[0])
-		// 日志内容
-		content := strings.TrimSpace(logs[1])
-		// 节点名称 fops.1.l71hvj98bsqx		master
-		name_Id, nodeName, _ := strings.Cut(name_Id_NodeName, "@")
-		// 服务ID和名称
-		var serverName string
-		var containerId string
-		if i := strings.LastIndex(name_Id, "."); i > 0 && i < len(name_Id)-1 {
-			serverName, containerId = name_Id[:i], name_Id[i+1:]
-		}
-
-		// 找到对应的容器，添加日志
-		if curContainer := lst.Find(func(item *ServiceLogVO) bool {
-			return item.ContainerId == containerId
-		}); curContainer != nil {
-			curContainer.Logs.Add(content)
-		} else {
-			lst.Add(ServiceLogVO{
-				ContainerId: containerId,
-				ServiceName: serverName,
-				NodeName:    nodeName,
-				Logs:        collections.NewList(content),
-			})
-		}
-	})
-	return lst, nil
+	return receiver.LogsContext(context.Background(), serviceIdOrServiceName, ServiceLogOptions{Tail: tail})
 }
 
 type ServiceLogVO struct {
-	ContainerId string
-	ServiceName string
-	NodeName    string
-	Logs        collections.List[string]
+	ContainerId string                            // 任务ID的前 12 位（与 docker service logs 输出的 fops.1.l71hvj98bsqx 一致），完整ID见 TaskId
+	ServiceName string                            // 任务名称 fops.1
+	NodeName    string                            // 节点名称
+	TaskId      string                            // 任务ID
+	Slot        int                               // 副本序号
+	NodeID      string                            // 节点ID
+	Logs        collections.List[string]          // 日志内容
+	Entries     collections.List[ServiceLogEntry] // 日志明细（时间、stdout/stderr）
 }
 
 // ServiceListVO 容器的名称 实例数量 副本数量 镜像（docker service ls）
@@ -316,7 +370,7 @@ func (receiver service) List() collections.List[ServiceListVO] {
 
 	// 4. 组装数据
 	services.Foreach(func(svc *ServiceListVO) {
-		svc.Spec.TaskTemplate.ContainerSpec.Image = strings.Split(svc.Spec.TaskTemplate.ContainerSpec.Image, "@")[0] // 去掉 digest 部分
+		svc.Spec.TaskTemplate.ContainerSpec.Image = TrimImageDigest(svc.Spec.TaskTemplate.ContainerSpec.Image) // 去掉 digest 部分
 	})
 	return services
 }
@@ -374,7 +428,7 @@ func (receiver service) PS(lstNode collections.List[DockerNodeVO], serviceName s
 			task.Status.ContainerStatus.ContainerID = task.Status.ContainerStatus.ContainerID[:12]
 		}
 
-		task.Spec.ContainerSpec.Image = strings.Split(task.Spec.ContainerSpec.Image, "@")[0] // 去掉 digest 部分
+		task.Spec.ContainerSpec.Image = TrimImageDigest(task.Spec.ContainerSpec.Image) // 去掉 digest 部分
 		slotMap[task.Slot] = append(slotMap[task.Slot], task)
 	}
 
@@ -473,54 +527,88 @@ func formatStateInfo(timestamp time.Time, state string) string {
 	}
 }
 
+// UpdateServiceConfig 将挂载到 targetPath 的配置替换为新的配置
 func (receiver service) UpdateServiceConfig(serviceName string, newConfigID, newConfigName, targetPath string) (bool, error) {
-	// 1. 获取原始 JSON 到 map 中
-	url := receiver.api.URL(fmt.Sprintf("/services/%s", serviceName))
-	resp, _ := receiver.api.httpClient.Get(url)
-	var raw map[string]interface{}
-	json.NewDecoder(resp.Body).Decode(&raw)
-	resp.Body.Close()
-
-	// 2. 提取 Spec 和 Version
-	spec := raw["Spec"].(map[string]interface{})
-	version := int(raw["Version"].(map[string]interface{})["Index"].(float64))
-
-	// 3. 动态修改 Configs 数组
-	taskTemplate := spec["TaskTemplate"].(map[string]interface{})
-	containerSpec := taskTemplate["ContainerSpec"].(map[string]interface{})
-	configs := containerSpec["Configs"].([]interface{})
-
-	for i := range configs {
-		cfg := configs[i].(map[string]interface{})
-		// 匹配 targetPath
-		file := cfg["File"].(map[string]interface{})
-		if file["Name"] == targetPath {
-			cfg["ConfigID"] = newConfigID
-			cfg["ConfigName"] = newConfigName
-			// 保留原有权限，只改 ID
-			break
+	_, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		configs := spec.TaskTemplate.ContainerSpec.Configs
+		for i := range configs {
+			// 匹配 targetPath，保留原有权限，只改 ID
+			if configs[i].File.Name == targetPath {
+				configs[i].ConfigID = newConfigID
+				configs[i].ConfigName = newConfigName
+				return nil
+			}
 		}
-	}
+		return &ServiceFileError{ServiceName: serviceName, Kind: "config", Target: targetPath, Err: ErrFileNotMounted}
+	})
+	return err == nil, err
+}
 
-	// 4. 发送更新
-	updateURL := receiver.api.URL(fmt.Sprintf("/services/%s/update?version=%d", serviceName, version))
-	body, _ := json.Marshal(spec) // 只发 Spec 部分
+// UpdateServiceSecret 将挂载到 targetPath 的密钥替换为新的密钥
+func (receiver service) UpdateServiceSecret(serviceName string, newSecretID, newSecretName, targetPath string) (bool, error) {
+	_, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		secrets := spec.TaskTemplate.ContainerSpec.Secrets
+		for i := range secrets {
+			// 匹配 targetPath，保留原有权限，只改 ID
+			if secrets[i].File.Name == targetPath {
+				secrets[i].SecretID = newSecretID
+				secrets[i].SecretName = newSecretName
+				return nil
+			}
+		}
+		return &ServiceFileError{ServiceName: serviceName, Kind: "secret", Target: targetPath, Err: ErrFileNotMounted}
+	})
+	return err == nil, err
+}
+
+// AttachSecret 为服务挂载密钥，target 为空时挂载到 /run/secrets/{secretName}；同一路径已挂载时替换
+func (receiver service) AttachSecret(serviceName string, secret ServiceSecretJson) error {
+	if secret.File.Name == "" {
+		secret.File.Name = secret.SecretName
+	}
+	if secret.File.UID == "" {
+		secret.File.UID, secret.File.GID, secret.File.Mode = "0", "0", 0444
+	}
+	_, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		secrets := spec.TaskTemplate.ContainerSpec.Secrets
+		for i := range secrets {
+			if secrets[i].File.Name == secret.File.Name {
+				secrets[i] = secret
+				return nil
+			}
+		}
+		spec.TaskTemplate.ContainerSpec.Secrets = append(secrets, secret)
+		return nil
+	})
+	return err
+}
 
-	req, _ := http.NewRequest("POST", updateURL, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := receiver.api.httpClient.Do(req)
-	resp.Body.Close()
-	return resp.StatusCode == 200, err
+// DetachSecret 移除挂载到 targetPath 的密钥（与 AttachSecret 一致，按挂载路径 File.Name 匹配）
+func (receiver service) DetachSecret(serviceName string, targetPath string) error {
+	_, err := receiver.Update(serviceName, func(spec *ServiceSpec) error {
+		var secrets []ServiceSecretJson
+		for _, item := range spec.TaskTemplate.ContainerSpec.Secrets {
+			if item.File.Name != targetPath {
+				secrets = append(secrets, item)
+			}
+		}
+		if len(secrets) == len(spec.TaskTemplate.ContainerSpec.Secrets) {
+			return &ServiceFileError{ServiceName: serviceName, Kind: "secret", Target: targetPath, Err: ErrFileNotMounted}
+		}
+		spec.TaskTemplate.ContainerSpec.Secrets = secrets
+		return nil
+	})
+	return err
 }
 
 // Inspect 查看服务详情
 func (receiver service) GetCurConfigVersion(serviceName string) (int, error) {
 	result, err := receiver.Inspect(serviceName)
+	// 构建匹配格式，例如 "fops_config_v%d"
+	format := fmt.Sprintf("%s_config_v", serviceName)
 	for _, config := range result.Spec.TaskTemplate.ContainerSpec.Configs {
-		// 构建匹配格式，例如 "fops_config_v%d"
-		format := fmt.Sprintf("%s_config_v", serviceName)
-		if len(config.ConfigName) > len(format) {
-			return parse.ToInt(config.ConfigName[len(format):]), nil
+		if version, found := strings.CutPrefix(config.ConfigName, format); found && version != "" {
+			return parse.ToInt(version), nil
 		}
 	}
 
@@ -0,0 +1,229 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 差异类型
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// SpecChange 单项差异
+type SpecChange struct {
+	Field  string // 字段 image env mounts ports resources constraints mode configs secrets labels networks args
+	Action string // added removed changed
+	Key    string // 环境变量名、挂载路径、端口等，单值字段为空
+	Old    string // 旧值
+	New    string // 新值
+}
+
+// ServiceSpecDiff 两个服务配置的差异
+type ServiceSpecDiff struct {
+	Changes []SpecChange
+}
+
+// HasChanges 是否存在差异
+func (receiver ServiceSpecDiff) HasChanges() bool {
+	return len(receiver.Changes) > 0
+}
+
+// String 可读的差异，用于部署日志
+//
+//	~ image: farseer/fops:v1 => farseer/fops:v2
+//	+ env DEBUG: true
+//	- mounts /data: volume data
+func (receiver ServiceSpecDiff) String() string {
+	var builder strings.Builder
+	for _, change := range receiver.Changes {
+		name := change.Field
+		if change.Key != "" {
+			name += " " + change.Key
+		}
+		switch change.Action {
+		case DiffAdded:
+			builder.WriteString(fmt.Sprintf("+ %s: %s\n", name, change.New))
+		case DiffRemoved:
+			builder.WriteString(fmt.Sprintf("- %s: %s\n", name, change.Old))
+		default:
+			builder.WriteString(fmt.Sprintf("~ %s: %s => %s\n", name, change.Old, change.New))
+		}
+	}
+	return builder.String()
+}
+
+// DiffServiceSpec 比较两个服务配置（oldSpec => newSpec）
+func DiffServiceSpec(oldSpec, newSpec ServiceSpec) ServiceSpecDiff {
+	var diff ServiceSpecDiff
+	oldContainer, newContainer := oldSpec.TaskTemplate.ContainerSpec, newSpec.TaskTemplate.ContainerSpec
+
+	diff.value("image", oldContainer.Image, newContainer.Image)
+	diff.value("command", strings.Join(oldContainer.Command, " "), strings.Join(newContainer.Command, " "))
+	diff.value("args", strings.Join(oldContainer.Args, " "), strings.Join(newContainer.Args, " "))
+	diff.value("mode", formatServiceMode(oldSpec.Mode), formatServiceMode(newSpec.Mode))
+	diff.keyed("env", envToMap(oldContainer.Env), envToMap(newContainer.Env))
+	diff.keyed("mounts", mountsToMap(oldContainer.Mounts), mountsToMap(newContainer.Mounts))
+	diff.keyed("ports", portsToMap(oldSpec.EndpointSpec), portsToMap(newSpec.EndpointSpec))
+	diff.keyed("resources", resourcesToMap(oldSpec.TaskTemplate.Resources), resourcesToMap(newSpec.TaskTemplate.Resources))
+	diff.keyed("constraints", sliceToMap(oldSpec.TaskTemplate.Placement.Constraints), sliceToMap(newSpec.TaskTemplate.Placement.Constraints))
+	diff.keyed("configs", configsToMap(oldContainer.Configs), configsToMap(newContainer.Configs))
+	diff.keyed("secrets", secretsToMap(oldContainer.Secrets), secretsToMap(newContainer.Secrets))
+	diff.keyed("networks", networksToMap(oldSpec.TaskTemplate.Networks), networksToMap(newSpec.TaskTemplate.Networks))
+	diff.keyed("labels", oldSpec.Labels, newSpec.Labels)
+	diff.keyed("container-labels", oldContainer.Labels, newContainer.Labels)
+	return diff
+}
+
+// DiffPrevious 比较服务的上一次配置与当前配置（PreviousSpec => Spec）
+func (receiver service) DiffPrevious(serviceName string) (ServiceSpecDiff, error) {
+	result, err := receiver.Inspect(serviceName)
+	if err != nil {
+		return ServiceSpecDiff{}, err
+	}
+	if !result.HasPreviousSpec() {
+		return ServiceSpecDiff{}, fmt.Errorf("service %s has no previous spec", serviceName)
+	}
+	return DiffServiceSpec(result.PreviousSpec, result.Spec), nil
+}
+
+func (receiver *ServiceSpecDiff) value(field, oldValue, newValue string) {
+	switch {
+	case oldValue == newValue:
+	case oldValue == "":
+		receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffAdded, New: newValue})
+	case newValue == "":
+		receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffRemoved, Old: oldValue})
+	default:
+		receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffChanged, Old: oldValue, New: newValue})
+	}
+}
+
+// keyed 按 key 比较，结果按 key 排序保证输出稳定
+func (receiver *ServiceSpecDiff) keyed(field string, oldValues, newValues map[string]string) {
+	keys := make([]string, 0, len(oldValues)+len(newValues))
+	for key := range oldValues {
+		keys = append(keys, key)
+	}
+	for key := range newValues {
+		if _, exists := oldValues[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, oldExists := oldValues[key]
+		newValue, newExists := newValues[key]
+		switch {
+		case !oldExists:
+			receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffAdded, Key: key, New: newValue})
+		case !newExists:
+			receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffRemoved, Key: key, Old: oldValue})
+		case oldValue != newValue:
+			receiver.Changes = append(receiver.Changes, SpecChange{Field: field, Action: DiffChanged, Key: key, Old: oldValue, New: newValue})
+		}
+	}
+}
+
+func formatServiceMode(mode ServiceMode) string {
+	switch {
+	case mode.Global != nil:
+		return "global"
+	case mode.GlobalJob != nil:
+		return "global-job"
+	case mode.ReplicatedJob != nil:
+		return fmt.Sprintf("replicated-job completions=%d concurrent=%d", mode.ReplicatedJob.TotalCompletions, mode.ReplicatedJob.MaxConcurrent)
+	default:
+		return fmt.Sprintf("replicated %d", mode.Replicated.Replicas)
+	}
+}
+
+func envToMap(env []string) map[string]string {
+	result := map[string]string{}
+	for _, item := range env {
+		key, value, _ := strings.Cut(item, "=")
+		result[key] = value
+	}
+	return result
+}
+
+func mountsToMap(mounts []Mount) map[string]string {
+	result := map[string]string{}
+	for _, mount := range mounts {
+		value := mount.Type + " " + mount.Source
+		if mount.ReadOnly {
+			value += " ro"
+		}
+		result[mount.Target] = value
+	}
+	return result
+}
+
+func portsToMap(endpointSpec *EndpointSpec) map[string]string {
+	result := map[string]string{}
+	if endpointSpec == nil {
+		return result
+	}
+	for _, port := range endpointSpec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		result[fmt.Sprintf("%d/%s", port.TargetPort, protocol)] = fmt.Sprintf("%d %s", port.PublishedPort, port.PublishMode)
+	}
+	return result
+}
+
+func resourcesToMap(resources ResourceRequirements) map[string]string {
+	result := map[string]string{}
+	add := func(key string, value int64, format func(int64) string) {
+		if value > 0 {
+			result[key] = format(value)
+		}
+	}
+	cpus := func(value int64) string { return strconv.FormatFloat(float64(value)/1e9, 'f', -1, 64) }
+	memory := func(value int64) string { return fmt.Sprintf("%.2fMB", float64(value)/1024/1024) }
+	add("limits.cpus", resources.Limits.NanoCPUs, cpus)
+	add("limits.memory", resources.Limits.MemoryBytes, memory)
+	add("limits.pids", resources.Limits.Pids, func(value int64) string { return strconv.FormatInt(value, 10) })
+	add("reservations.cpus", resources.Reservations.NanoCPUs, cpus)
+	add("reservations.memory", resources.Reservations.MemoryBytes, memory)
+	return result
+}
+
+func sliceToMap(values []string) map[string]string {
+	result := map[string]string{}
+	for _, value := range values {
+		result[value] = value
+	}
+	return result
+}
+
+func configsToMap(configs []ServiceConfigJson) map[string]string {
+	result := map[string]string{}
+	for _, config := range configs {
+		result[config.File.Name] = config.ConfigName
+	}
+	return result
+}
+
+func secretsToMap(secrets []ServiceSecretJson) map[string]string {
+	result := map[string]string{}
+	for _, secret := range secrets {
+		result[secret.File.Name] = secret.SecretName
+	}
+	return result
+}
+
+func networksToMap(networks []NetworkAttachmentConfig) map[string]string {
+	result := map[string]string{}
+	for _, network := range networks {
+		result[network.Target] = strings.Join(network.Aliases, ",")
+	}
+	return result
+}
@@ -0,0 +1,20 @@
+package docker
+
+import "testing"
+
+func TestDiffServiceSpec(t *testing.T) {
+	oldSpec, _ := NewServiceSpec("fops", "farseer/fops:v1").Env("A", "1").Env("B", "2").Replicas(2).Build()
+	newSpec, _ := NewServiceSpec("fops", "farseer/fops:v2").Env("A", "3").Env("C", "4").Replicas(2).Build()
+
+	diff := DiffServiceSpec(oldSpec, newSpec)
+	want := "~ image: farseer/fops:v1 => farseer/fops:v2\n" +
+		"~ env A: 1 => 3\n" +
+		"- env B: 2\n" +
+		"+ env C: 4\n"
+	if got := diff.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if DiffServiceSpec(oldSpec, oldSpec).HasChanges() {
+		t.Fatal("expected no changes for identical specs")
+	}
+}
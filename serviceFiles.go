@@ -0,0 +1,321 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// 挂载配置、密钥失败的原因
+var (
+	ErrFileNotMounted       = errors.New("not mounted")                      // 移除的路径没有挂载
+	ErrFileDuplicateTarget  = errors.New("target is changed more than once") // 同一个路径在一次更新中出现多次
+	ErrFileTargetRequired   = errors.New("target is required")               // 没有指定挂载路径
+	ErrFileReferenceMissing = errors.New("id or name is required")           // 没有指定配置、密钥
+)
+
+// ServiceFileError 更新服务挂载的配置、密钥失败
+type ServiceFileError struct {
+	ServiceName string // 服务名称
+	Kind        string // config secret
+	Target      string // 挂载路径
+	Err         error  // ErrFileNotMounted、ErrFileDuplicateTarget 等，或查询配置、密钥时的错误
+}
+
+func (receiver *ServiceFileError) Error() string {
+	return fmt.Sprintf("service %s %s %s: %v", receiver.ServiceName, receiver.Kind, receiver.Target, receiver.Err)
+}
+
+func (receiver *ServiceFileError) Unwrap() error {
+	return receiver.Err
+}
+
+// ServiceFilesUpdate 一次提交的配置、密钥变更，按挂载路径（File.Name）匹配
+type ServiceFilesUpdate struct {
+	SetConfigs    []ServiceConfigJson // 新增或替换配置（ConfigID 为空时按 ConfigName 查询）
+	RemoveConfigs []string            // 移除挂载到这些路径的配置
+	SetSecrets    []ServiceSecretJson // 新增或替换密钥（SecretID 为空时按 SecretName 查询，路径为空时为 SecretName）
+	RemoveSecrets []string            // 移除挂载到这些路径的密钥
+}
+
+// IsEmpty 没有任何变更
+func (receiver ServiceFilesUpdate) IsEmpty() bool {
+	return len(receiver.SetConfigs)+len(receiver.RemoveConfigs)+len(receiver.SetSecrets)+len(receiver.RemoveSecrets) == 0
+}
+
+// UpdateFiles 在一次服务更新中新增、替换、移除多个配置和密钥，任何一项失败时不会提交
+func (receiver service) UpdateFiles(serviceName string, update ServiceFilesUpdate) (ServiceUpdateResult, error) {
+	return receiver.UpdateFilesContext(context.Background(), serviceName, update)
+}
+
+// UpdateFilesContext 同 UpdateFiles（支持 ctx 控制超时/取消）
+func (receiver service) UpdateFilesContext(ctx context.Context, serviceName string, update ServiceFilesUpdate) (ServiceUpdateResult, error) {
+	if update.IsEmpty() {
+		return ServiceUpdateResult{}, nil
+	}
+
+	// 1. 补全 ID：只有名称时查询配置、密钥
+	update.SetConfigs = append([]ServiceConfigJson(nil), update.SetConfigs...)
+	for i, item := range update.SetConfigs {
+		if item.ConfigID != "" || item.ConfigName == "" {
+			continue
+		}
+		info, err := config{api: receiver.api}.Inspect(item.ConfigName)
+		if err != nil {
+			return ServiceUpdateResult{}, &ServiceFileError{ServiceName: serviceName, Kind: "config", Target: item.File.Name, Err: err}
+		}
+		update.SetConfigs[i].ConfigID = info.ID
+	}
+	update.SetSecrets = append([]ServiceSecretJson(nil), update.SetSecrets...)
+	for i, item := range update.SetSecrets {
+		if item.SecretID != "" || item.SecretName == "" {
+			continue
+		}
+		info, err := secret{api: receiver.api}.Inspect(item.SecretName)
+		if err != nil {
+			return ServiceUpdateResult{}, &ServiceFileError{ServiceName: serviceName, Kind: "secret", Target: item.File.Name, Err: err}
+		}
+		update.SetSecrets[i].SecretID = info.ID
+	}
+
+	// 2. 一次提交所有变更
+	return receiver.UpdateContext(ctx, serviceName, func(spec *ServiceSpec) error {
+		return applyServiceFiles(serviceName, &spec.TaskTemplate.ContainerSpec, update)
+	})
+}
+
+// applyServiceFiles 将配置、密钥的变更应用到 ContainerSpec，先校验全部变更，校验失败时不修改
+func applyServiceFiles(serviceName string, containerSpec *ContainerSpec, update ServiceFilesUpdate) error {
+	newError := func(kind, target string, err error) error {
+		return &ServiceFileError{ServiceName: serviceName, Kind: kind, Target: target, Err: err}
+	}
+
+	// 1. 配置
+	configs := append([]ServiceConfigJson(nil), containerSpec.Configs...)
+	configTargets := map[string]bool{}
+	for _, item := range update.SetConfigs {
+		switch {
+		case item.File.Name == "":
+			return newError("config", item.ConfigName, ErrFileTargetRequired)
+		case item.ConfigID == "" && item.ConfigName == "":
+			return newError("config", item.File.Name, ErrFileReferenceMissing)
+		case configTargets[item.File.Name]:
+			return newError("config", item.File.Name, ErrFileDuplicateTarget)
+		}
+		configTargets[item.File.Name] = true
+
+		index := -1
+		for i := range configs {
+			if configs[i].File.Name == item.File.Name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			if item.File.UID == "" {
+				item.File.UID, item.File.GID, item.File.Mode = "0", "0", 0444
+			}
+			configs = append(configs, item)
+			continue
+		}
+		// 替换时未指定权限则保留原有权限
+		if item.File.UID == "" {
+			item.File = configs[index].File
+		}
+		configs[index] = item
+	}
+	for _, target := range update.RemoveConfigs {
+		if configTargets[target] {
+			return newError("config", target, ErrFileDuplicateTarget)
+		}
+		configTargets[target] = true
+
+		remaining := configs[:0:0]
+		for _, item := range configs {
+			if item.File.Name != target {
+				remaining = append(remaining, item)
+			}
+		}
+		if len(remaining) == len(configs) {
+			return newError("config", target, ErrFileNotMounted)
+		}
+		configs = remaining
+	}
+
+	// 2. 密钥
+	secrets := append([]ServiceSecretJson(nil), containerSpec.Secrets...)
+	secretTargets := map[string]bool{}
+	for _, item := range update.SetSecrets {
+		if item.File.Name == "" {
+			item.File.Name = item.SecretName
+		}
+		switch {
+		case item.SecretID == "" && item.SecretName == "":
+			return newError("secret", item.File.Name, ErrFileReferenceMissing)
+		case secretTargets[item.File.Name]:
+			return newError("secret", item.File.Name, ErrFileDuplicateTarget)
+		}
+		secretTargets[item.File.Name] = true
+
+		index := -1
+		for i := range secrets {
+			if secrets[i].File.Name == item.File.Name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			if item.File.UID == "" {
+				item.File.UID, item.File.GID, item.File.Mode = "0", "0", 0444
+			}
+			secrets = append(secrets, item)
+			continue
+		}
+		if item.File.UID == "" {
+			item.File = secrets[index].File
+		}
+		secrets[index] = item
+	}
+	for _, target := range update.RemoveSecrets {
+		if secretTargets[target] {
+			return newError("secret", target, ErrFileDuplicateTarget)
+		}
+		secretTargets[target] = true
+
+		remaining := secrets[:0:0]
+		for _, item := range secrets {
+			if item.File.Name != target {
+				remaining = append(remaining, item)
+			}
+		}
+		if len(remaining) == len(secrets) {
+			return newError("secret", target, ErrFileNotMounted)
+		}
+		secrets = remaining
+	}
+
+	containerSpec.Configs, containerSpec.Secrets = configs, secrets
+	return nil
+}
+
+// versionedFileName 版本化的配置、密钥名称：fops_config_v3 => fops_config, 3
+var versionedFileName = regexp.MustCompile(`^(.+)_v(\d+)$`)
+
+// versionedFile 版本化的配置、密钥
+type versionedFile struct {
+	ID    string
+	Name  string
+	Owned bool // 带有 owner_service=<app> 标签
+}
+
+// SyncFiles 将服务挂载的版本化配置、密钥更新到各自的最新版本，在一次服务更新中提交
+// 只处理属于 appName 的版本化名称（{name}_v{N}）：<app>_config_vN、<app>_secret_vN，或带有 owner_service=<app> 标签的配置、密钥
+// 返回：是否更新了服务
+func (receiver *Client) SyncFiles(appName string) (bool, error) {
+	svc, err := receiver.Service.Inspect(appName)
+	if err != nil {
+		return false, err
+	}
+	containerSpec := svc.Spec.TaskTemplate.ContainerSpec
+
+	var update ServiceFilesUpdate
+	if len(containerSpec.Configs) > 0 {
+		owned, err := receiver.Config.List(ConfigListOptions{Labels: map[string]string{ConfigOwnerLabel: appName}})
+		if err != nil {
+			return false, err
+		}
+		named, err := receiver.Config.List(ConfigListOptions{Name: appName + "_config_v"})
+		if err != nil {
+			return false, err
+		}
+		var files []versionedFile
+		owned.Foreach(func(item *ConfigInfo) {
+			files = append(files, versionedFile{ID: item.ID, Name: item.Spec.Name, Owned: true})
+		})
+		named.Foreach(func(item *ConfigInfo) {
+			files = append(files, versionedFile{ID: item.ID, Name: item.Spec.Name})
+		})
+		for _, item := range containerSpec.Configs {
+			if latest, ok := latestVersionedFile(appName, "config", item.ConfigID, item.ConfigName, files); ok {
+				item.ConfigID, item.ConfigName = latest.ID, latest.Name
+				update.SetConfigs = append(update.SetConfigs, item)
+			}
+		}
+	}
+	if len(containerSpec.Secrets) > 0 {
+		owned, err := receiver.Secret.List(SecretListOptions{Labels: map[string]string{ConfigOwnerLabel: appName}})
+		if err != nil {
+			return false, err
+		}
+		named, err := receiver.Secret.List(SecretListOptions{Name: appName + "_secret_v"})
+		if err != nil {
+			return false, err
+		}
+		var files []versionedFile
+		owned.Foreach(func(item *SecretInfo) {
+			files = append(files, versionedFile{ID: item.ID, Name: item.Spec.Name, Owned: true})
+		})
+		named.Foreach(func(item *SecretInfo) {
+			files = append(files, versionedFile{ID: item.ID, Name: item.Spec.Name})
+		})
+		for _, item := range containerSpec.Secrets {
+			if latest, ok := latestVersionedFile(appName, "secret", item.SecretID, item.SecretName, files); ok {
+				item.SecretID, item.SecretName = latest.ID, latest.Name
+				update.SetSecrets = append(update.SetSecrets, item)
+			}
+		}
+	}
+
+	if update.IsEmpty() {
+		return false, nil
+	}
+	if _, err = receiver.Service.UpdateFiles(appName, update); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// latestVersionedFile 挂载的 name 属于 appName 时，在 files 中查找同一系列中版本号更大的最新版本
+// 系列为 <app>_<kind> 时按名称匹配，其它系列只在挂载的对象和新版本都带有 owner_service=<app> 标签时匹配
+func latestVersionedFile(appName string, kind string, id string, name string, files []versionedFile) (versionedFile, bool) {
+	series, version, ok := parseVersionedFileName(name)
+	if !ok {
+		return versionedFile{}, false
+	}
+	byName := series == appName+"_"+kind
+	if !byName {
+		owned := false
+		for _, file := range files {
+			if file.Owned && (file.ID == id || file.Name == name) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return versionedFile{}, false
+		}
+	}
+
+	var latest versionedFile
+	for _, file := range files {
+		if !byName && !file.Owned {
+			continue
+		}
+		if curSeries, curVersion, ok := parseVersionedFileName(file.Name); ok && curSeries == series && curVersion > version {
+			latest, version = file, curVersion
+		}
+	}
+	return latest, latest.ID != ""
+}
+
+// parseVersionedFileName 解析版本化的名称：fops_config_v3 => fops_config, 3
+func parseVersionedFileName(name string) (string, int, bool) {
+	match := versionedFileName.FindStringSubmatch(name)
+	if match == nil {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(match[2])
+	return match[1], version, err == nil
+}
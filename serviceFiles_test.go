@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestApplyServiceFiles(t *testing.T) {
+	containerSpec := ContainerSpec{
+		Configs: []ServiceConfigJson{
+			{ConfigID: "c1", ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/config.yaml", UID: "0", GID: "0", Mode: 0400}},
+			{ConfigID: "n1", ConfigName: "fops_nginx_v1", File: ServiceConfigFileJson{Name: "/etc/nginx.conf", UID: "0", GID: "0", Mode: 0444}},
+		},
+		Secrets: []ServiceSecretJson{{SecretID: "s1", SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "fops_secret_v1"}}},
+	}
+
+	err := applyServiceFiles("fops", &containerSpec, ServiceFilesUpdate{
+		SetConfigs:    []ServiceConfigJson{{ConfigID: "c2", ConfigName: "fops_config_v2", File: ServiceConfigFileJson{Name: "/app/config.yaml"}}},
+		RemoveConfigs: []string{"/etc/nginx.conf"},
+		SetSecrets:    []ServiceSecretJson{{SecretID: "t1", SecretName: "fops_token"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containerSpec.Configs) != 1 || containerSpec.Configs[0].ConfigID != "c2" || containerSpec.Configs[0].File.Mode != 0400 {
+		t.Fatalf("unexpected configs: %+v", containerSpec.Configs)
+	}
+	if len(containerSpec.Secrets) != 2 || containerSpec.Secrets[1].File.Name != "fops_token" {
+		t.Fatalf("unexpected secrets: %+v", containerSpec.Secrets)
+	}
+
+	// 任何一项失败时不修改
+	err = applyServiceFiles("fops", &containerSpec, ServiceFilesUpdate{
+		SetConfigs:    []ServiceConfigJson{{ConfigID: "c3", File: ServiceConfigFileJson{Name: "/app/config.yaml"}}},
+		RemoveSecrets: []string{"missing"},
+	})
+	var fileErr *ServiceFileError
+	if !errors.As(err, &fileErr) || !errors.Is(err, ErrFileNotMounted) || fileErr.Kind != "secret" {
+		t.Fatalf("expected secret not mounted error, got %v", err)
+	}
+	if containerSpec.Configs[0].ConfigID != "c2" {
+		t.Fatal("spec should not be modified when the update fails")
+	}
+}
+
+func TestParseVersionedFileName(t *testing.T) {
+	if series, version, ok := parseVersionedFileName("fops_config_v12"); !ok || series != "fops_config" || version != 12 {
+		t.Fatalf("got %s %d %v", series, version, ok)
+	}
+	if _, _, ok := parseVersionedFileName("fops_config"); ok {
+		t.Fatal("expected unversioned name")
+	}
+}
+
+func TestSyncFiles(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	owner := map[string]string{ConfigOwnerLabel: "fops"}
+	ids := map[string]string{}
+	for _, name := range []string{"fops_config_v1", "fops_config_v3", "nginx_v1", "nginx_v2"} {
+		ids[name] = swarm.addObject("configs", name, name, nil)
+	}
+	// 名称不是 fops_config_vN，但带有 owner_service=fops 标签
+	for _, name := range []string{"fops_tls_v1", "fops_tls_v2"} {
+		ids[name] = swarm.addObject("configs", name, name, owner)
+	}
+	for _, name := range []string{"fops_secret_v1", "fops_secret_v2", "db_password_v1", "db_password_v2"} {
+		ids[name] = swarm.addObject("secrets", name, name, nil)
+	}
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Config(ServiceConfigJson{ConfigID: ids["fops_config_v1"], ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml", UID: "0", GID: "0", Mode: 0400}}).
+		Config(ServiceConfigJson{ConfigID: ids["nginx_v1"], ConfigName: "nginx_v1", File: ServiceConfigFileJson{Name: "/etc/nginx.conf"}}).
+		Config(ServiceConfigJson{ConfigID: ids["fops_tls_v1"], ConfigName: "fops_tls_v1", File: ServiceConfigFileJson{Name: "/app/tls.pem"}}).
+		Secret(ServiceSecretJson{SecretID: ids["fops_secret_v1"], SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "db"}}).
+		Secret(ServiceSecretJson{SecretID: ids["db_password_v1"], SecretName: "db_password_v1", File: ServiceConfigFileJson{Name: "db_password"}}).
+		Build()
+	swarm.addService(spec)
+
+	updated, err := client.SyncFiles("fops")
+	if err != nil || !updated {
+		t.Fatalf("SyncFiles() = %v, %v", updated, err)
+	}
+	// 只更新属于 fops 的系列：按名称前缀或 owner_service 标签；其它服务的 *_vN 不受影响
+	containerSpec := swarm.service("fops").Spec.TaskTemplate.ContainerSpec
+	configs := []string{containerSpec.Configs[0].ConfigName, containerSpec.Configs[1].ConfigName, containerSpec.Configs[2].ConfigName}
+	if configs[0] != "fops_config_v3" || configs[1] != "nginx_v1" || configs[2] != "fops_tls_v2" || containerSpec.Configs[0].File.Mode != 0400 {
+		t.Fatalf("configs = %v", configs)
+	}
+	if containerSpec.Configs[0].ConfigID != ids["fops_config_v3"] {
+		t.Fatalf("config id = %s", containerSpec.Configs[0].ConfigID)
+	}
+	if containerSpec.Secrets[0].SecretName != "fops_secret_v2" || containerSpec.Secrets[1].SecretName != "db_password_v1" {
+		t.Fatalf("secrets = %#v", containerSpec.Secrets)
+	}
+
+	// 已经是最新版本
+	if updated, err = client.SyncFiles("fops"); err != nil || updated || len(swarm.updates) != 1 {
+		t.Fatalf("SyncFiles() = %v, %v, updates %v", updated, err, swarm.updates)
+	}
+}
+
+func TestSyncConfig(t *testing.T) {
+	swarm, client := newFakeSwarm(t)
+	ids := map[string]string{}
+	for version := 1; version <= 2; version++ {
+		name := ConfigVersionName("fops", version)
+		ids[name] = swarm.addObject("configs", name, name, map[string]string{ConfigOwnerLabel: "fops", ConfigVersionLabel: fmt.Sprint(version)})
+	}
+	for _, name := range []string{"fops_secret_v1", "fops_secret_v2"} {
+		ids[name] = swarm.addObject("secrets", name, name, nil)
+	}
+	spec, _ := NewServiceSpec("fops", "farseer/fops:v1").
+		Config(ServiceConfigJson{ConfigID: ids["fops_config_v1"], ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml", UID: "0", GID: "0", Mode: 0400}}).
+		Secret(ServiceSecretJson{SecretID: ids["fops_secret_v1"], SecretName: "fops_secret_v1", File: ServiceConfigFileJson{Name: "db"}}).
+		Build()
+	swarm.addService(spec)
+
+	// targetConfigPath 没有挂载版本化配置
+	if client.SyncConfig("fops", "/app/other.yaml") || len(swarm.updates) != 0 {
+		t.Fatalf("SyncConfig() should not update, updates %v", swarm.updates)
+	}
+	if !client.SyncConfig("fops", "/app/farseer.yaml") {
+		t.Fatal("SyncConfig() = false, want true")
+	}
+	// 只更新 targetConfigPath 的配置，密钥保持不变
+	containerSpec := swarm.service("fops").Spec.TaskTemplate.ContainerSpec
+	if containerSpec.Configs[0].ConfigName != "fops_config_v2" || containerSpec.Configs[0].ConfigID != ids["fops_config_v2"] || containerSpec.Configs[0].File.Mode != 0400 {
+		t.Fatalf("configs = %#v", containerSpec.Configs)
+	}
+	if containerSpec.Secrets[0].SecretName != "fops_secret_v1" {
+		t.Fatalf("secrets = %#v", containerSpec.Secrets)
+	}
+	if client.SyncConfig("fops", "/app/farseer.yaml") || len(swarm.updates) != 1 {
+		t.Fatalf("SyncConfig() should not update the latest version, updates %v", swarm.updates)
+	}
+}
@@ -63,130 +63,13 @@ type ServiceInspectJson struct {
 	Version struct {
 		Index int `json:"Index"`
 	} `json:"Version"`
-	CreatedAt time.Time `json:"CreatedAt"`
-	UpdatedAt time.Time `json:"UpdatedAt"`
-	Spec      struct {
-		Name         string            `json:"Name"`
-		Labels       map[string]string `json:"Labels"`
-		TaskTemplate struct {
-			ContainerSpec struct {
-				Image  string `json:"Image"`
-				Init   bool   `json:"Init"`
-				Mounts []struct {
-					Type   string `json:"Type"`
-					Source string `json:"Source"`
-					Target string `json:"Target"`
-				} `json:"Mounts"`
-				StopGracePeriod int64 `json:"StopGracePeriod"`
-				DNSConfig       struct {
-				} `json:"DNSConfig"`
-				Isolation string              `json:"Isolation"`
-				Configs   []ServiceConfigJson `json:"Configs,omitempty"`
-			} `json:"ContainerSpec"`
-			Resources struct {
-				Limits struct {
-				} `json:"Limits"`
-				Reservations struct {
-				} `json:"Reservations"`
-			} `json:"Resources"`
-			RestartPolicy struct {
-				Condition   string `json:"Condition"`
-				Delay       int64  `json:"Delay"`
-				MaxAttempts int    `json:"MaxAttempts"`
-			} `json:"RestartPolicy"`
-			Placement struct {
-				Constraints []string `json:"Constraints"`
-				Platforms   []struct {
-					Architecture string `json:"Architecture"`
-					OS           string `json:"OS"`
-				} `json:"Platforms"`
-			} `json:"Placement"`
-			Networks []struct {
-				Target string `json:"Target"`
-			} `json:"Networks"`
-			ForceUpdate int    `json:"ForceUpdate"`
-			Runtime     string `json:"Runtime"`
-		} `json:"TaskTemplate"`
-		Mode struct {
-			Replicated struct {
-				Replicas int `json:"Replicas"`
-			} `json:"Replicated"`
-		} `json:"Mode"`
-		UpdateConfig struct {
-			Parallelism     int    `json:"Parallelism"`
-			Delay           int64  `json:"Delay"`
-			FailureAction   string `json:"FailureAction"`
-			Monitor         int64  `json:"Monitor"`
-			MaxFailureRatio int    `json:"MaxFailureRatio"`
-			Order           string `json:"Order"`
-		} `json:"UpdateConfig"`
-		RollbackConfig struct {
-			Parallelism     int    `json:"Parallelism"`
-			FailureAction   string `json:"FailureAction"`
-			Monitor         int64  `json:"Monitor"`
-			MaxFailureRatio int    `json:"MaxFailureRatio"`
-			Order           string `json:"Order"`
-		} `json:"RollbackConfig"`
-		EndpointSpec struct {
-			Mode string `json:"Mode"`
-		} `json:"EndpointSpec"`
-	} `json:"Spec"`
-	PreviousSpec struct {
-		Name         string            `json:"Name"`
-		Labels       map[string]string `json:"Labels"`
-		TaskTemplate struct {
-			ContainerSpec struct {
-				Image  string `json:"Image"`
-				Init   bool   `json:"Init"`
-				Mounts []struct {
-					Type   string `json:"Type"`
-					Source string `json:"Source"`
-					Target string `json:"Target"`
-				} `json:"Mounts"`
-				DNSConfig struct {
-				} `json:"DNSConfig"`
-				Isolation string `json:"Isolation"`
-			} `json:"ContainerSpec"`
-			Resources struct {
-				Limits struct {
-				} `json:"Limits"`
-				Reservations struct {
-				} `json:"Reservations"`
-			} `json:"Resources"`
-			Placement struct {
-				Constraints []string `json:"Constraints"`
-				Platforms   []struct {
-					Architecture string `json:"Architecture"`
-					OS           string `json:"OS"`
-				} `json:"Platforms"`
-			} `json:"Placement"`
-			Networks []struct {
-				Target string `json:"Target"`
-			} `json:"Networks"`
-			ForceUpdate int    `json:"ForceUpdate"`
-			Runtime     string `json:"Runtime"`
-		} `json:"TaskTemplate"`
-		Mode struct {
-			Replicated struct {
-				Replicas int `json:"Replicas"`
-			} `json:"Replicated"`
-		} `json:"Mode"`
-		UpdateConfig struct {
-			Parallelism     int    `json:"Parallelism"`
-			Delay           int64  `json:"Delay"`
-			FailureAction   string `json:"FailureAction"`
-			Monitor         int64  `json:"Monitor"`
-			MaxFailureRatio int    `json:"MaxFailureRatio"`
-			Order           string `json:"Order"`
-		} `json:"UpdateConfig"`
-		EndpointSpec struct {
-			Mode string `json:"Mode"`
-		} `json:"EndpointSpec"`
-	} `json:"PreviousSpec"`
-	Endpoint struct {
-		Spec struct {
-			Mode string `json:"Mode"`
-		} `json:"Spec"`
+	CreatedAt    time.Time   `json:"CreatedAt"`
+	UpdatedAt    time.Time   `json:"UpdatedAt"`
+	Spec         ServiceSpec `json:"Spec"`         // 当前配置
+	PreviousSpec ServiceSpec `json:"PreviousSpec"` // 上一次的配置（没有更新过时 Name 为空）
+	Endpoint     struct {
+		Spec       EndpointSpec `json:"Spec"`
+		Ports      []PortConfig `json:"Ports"` // 实际发布的端口（未指定 PublishedPort 时由 swarm 分配）
 		VirtualIPs []struct {
 			NetworkID string `json:"NetworkID"`
 			Addr      string `json:"Addr"`
@@ -198,6 +81,17 @@ type ServiceInspectJson struct {
 		CompletedAt time.Time `json:"CompletedAt"`
 		Message     string    `json:"Message"`
 	} `json:"UpdateStatus"`
+	JobStatus *struct {
+		JobIteration struct {
+			Index int `json:"Index"`
+		} `json:"JobIteration"`
+		LastExecution time.Time `json:"LastExecution"`
+	} `json:"JobStatus,omitempty"` // 一次性任务（replicated-job、global-job）的执行状态
+}
+
+// HasPreviousSpec 是否存在上一次的配置
+func (receiver ServiceInspectJson) HasPreviousSpec() bool {
+	return receiver.PreviousSpec.Name != ""
 }
 
 type ServiceIdInspectJson struct {
@@ -208,41 +102,10 @@ type ServiceIdInspectJson struct {
 	CreatedAt time.Time         `json:"CreatedAt"`
 	UpdatedAt time.Time         `json:"UpdatedAt"`
 	Labels    map[string]string `json:"Labels"`
-	Spec      struct {
-		ContainerSpec struct {
-			Image  string   `json:"Image"`
-			Env    []string `json:"Env"`
-			Init   bool     `json:"Init"`
-			Mounts []struct {
-				Type   string `json:"Type"`
-				Source string `json:"Source"`
-				Target string `json:"Target"`
-			} `json:"Mounts"`
-			DNSConfig struct {
-			} `json:"DNSConfig"`
-			Isolation string `json:"Isolation"`
-		} `json:"ContainerSpec"`
-		Resources struct {
-			Limits struct {
-			} `json:"Limits"`
-			Reservations struct {
-			} `json:"Reservations"`
-		} `json:"Resources"`
-		Placement struct {
-			Constraints []string `json:"Constraints"`
-			Platforms   []struct {
-				Architecture string `json:"Architecture"`
-				OS           string `json:"OS"`
-			} `json:"Platforms"`
-		} `json:"Placement"`
-		Networks []struct {
-			Target string `json:"Target"`
-		} `json:"Networks"`
-		ForceUpdate int `json:"ForceUpdate"`
-	} `json:"Spec"`
-	ServiceID string `json:"ServiceID"`
-	Slot      int    `json:"Slot"`
-	NodeID    string `json:"NodeID"`
+	Spec      TaskSpec          `json:"Spec"`
+	ServiceID string            `json:"ServiceID"`
+	Slot      int               `json:"Slot"`
+	NodeID    string            `json:"NodeID"`
 	Status    struct {
 		Timestamp       time.Time `json:"Timestamp"`
 		State           string    `json:"State"`
@@ -256,7 +119,10 @@ type ServiceIdInspectJson struct {
 		PortStatus struct {
 		} `json:"PortStatus"`
 	} `json:"Status"`
-	DesiredState        string `json:"DesiredState"`
+	DesiredState string `json:"DesiredState"`
+	JobIteration *struct {
+		Index int `json:"Index"`
+	} `json:"JobIteration,omitempty"` // 一次性任务所属的执行批次，对应服务的 JobStatus.JobIteration
 	NetworksAttachments []struct {
 		Network struct {
 			ID      string `json:"ID"`
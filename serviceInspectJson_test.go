@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// docker 24 返回的 GET /services/{id}
+const serviceInspectPayload = `{
+	"ID": "9mnpnzenvg8p8tdbtq4wvbkcz",
+	"Version": {"Index": 19},
+	"CreatedAt": "2024-03-07T21:05:51.880065305Z",
+	"UpdatedAt": "2024-03-07T21:07:29.962229872Z",
+	"Spec": {
+		"Name": "fops",
+		"Labels": {"com.docker.stack.namespace": "farseer"},
+		"TaskTemplate": {
+			"ContainerSpec": {
+				"Image": "farseer/fops:v2@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				"Env": ["TZ=Asia/Shanghai"],
+				"Init": false,
+				"Mounts": [{"Type": "volume", "Source": "fops_data", "Target": "/data", "VolumeOptions": {"Subpath": "logs"}}],
+				"StopGracePeriod": 10000000000,
+				"DNSConfig": {},
+				"Configs": [{"File": {"Name": "/app/farseer.yaml", "UID": "0", "GID": "0", "Mode": 292}, "ConfigID": "m8xq9kzq6a7y", "ConfigName": "fops_config_v3"}],
+				"Isolation": "default"
+			},
+			"Resources": {"Limits": {"NanoCPUs": 500000000, "MemoryBytes": 536870912}, "Reservations": {}},
+			"RestartPolicy": {"Condition": "any", "Delay": 5000000000, "MaxAttempts": 0},
+			"Placement": {"Constraints": ["node.role==manager"], "Platforms": [{"Architecture": "amd64", "OS": "linux"}]},
+			"Networks": [{"Target": "u5nvf2sbdq9hcrnwgqxnbqkfq", "Aliases": ["fops"]}],
+			"ForceUpdate": 0,
+			"Runtime": "container"
+		},
+		"Mode": {"Replicated": {"Replicas": 2}},
+		"UpdateConfig": {"Parallelism": 1, "Delay": 10000000000, "FailureAction": "pause", "Monitor": 5000000000, "MaxFailureRatio": 0.2, "Order": "start-first"},
+		"RollbackConfig": {"Parallelism": 1, "FailureAction": "pause", "Monitor": 5000000000, "MaxFailureRatio": 0, "Order": "stop-first"},
+		"EndpointSpec": {"Mode": "vip", "Ports": [{"Protocol": "tcp", "TargetPort": 8888, "PublishedPort": 8888, "PublishMode": "ingress"}]}
+	},
+	"PreviousSpec": {
+		"Name": "fops",
+		"Labels": {"com.docker.stack.namespace": "farseer"},
+		"TaskTemplate": {
+			"ContainerSpec": {"Image": "farseer/fops:v1@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", "Isolation": "default"},
+			"ForceUpdate": 0,
+			"Runtime": "container"
+		},
+		"Mode": {"Replicated": {"Replicas": 2}},
+		"EndpointSpec": {"Mode": "vip"}
+	},
+	"Endpoint": {
+		"Spec": {"Mode": "vip", "Ports": [{"Protocol": "tcp", "TargetPort": 8888, "PublishedPort": 8888, "PublishMode": "ingress"}]},
+		"Ports": [{"Protocol": "tcp", "TargetPort": 8888, "PublishedPort": 8888, "PublishMode": "ingress"}],
+		"VirtualIPs": [{"NetworkID": "4qvuz4ko70xaltuqbt8956gd1", "Addr": "10.255.0.2/16"}]
+	},
+	"UpdateStatus": {"State": "completed", "StartedAt": "2024-03-07T21:06:01.511356Z", "CompletedAt": "2024-03-07T21:07:29.962226Z", "Message": "update completed"}
+}`
+
+func TestServiceInspectDecode(t *testing.T) {
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/fops" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"service fops not found"}`))
+			return
+		}
+		w.Write([]byte(serviceInspectPayload))
+	})
+
+	svc, err := service{api: api}.Inspect("fops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, containerSpec := svc.Spec, svc.Spec.TaskTemplate.ContainerSpec
+	tests := map[string]bool{
+		"ID":                     svc.ID == "9mnpnzenvg8p8tdbtq4wvbkcz" && svc.Version.Index == 19,
+		"UpdatedAt":              svc.UpdatedAt.Equal(time.Date(2024, 3, 7, 21, 7, 29, 962229872, time.UTC)),
+		"Image":                  containerSpec.Image == "farseer/fops:v2" && svc.PreviousSpec.TaskTemplate.ContainerSpec.Image == "farseer/fops:v1",
+		"Init":                   containerSpec.Init != nil && !*containerSpec.Init,
+		"Mounts":                 len(containerSpec.Mounts) == 1 && containerSpec.Mounts[0].Source == "fops_data",
+		"Configs":                len(containerSpec.Configs) == 1 && containerSpec.Configs[0].File.Name == "/app/farseer.yaml",
+		"Resources":              spec.TaskTemplate.Resources.Limits.MemoryBytes == 536870912,
+		"RestartPolicy":          spec.TaskTemplate.GetRestartPolicy().Condition == "any",
+		"Placement":              spec.TaskTemplate.Placement.Constraints[0] == "node.role==manager",
+		"Networks":               spec.TaskTemplate.Networks[0].Aliases[0] == "fops",
+		"Replicas":               spec.Mode.Replicated.Replicas == 2 && !spec.Mode.IsGlobal(),
+		"UpdateConfig":           spec.GetUpdateConfig().MaxFailureRatio == 0.2 && spec.GetUpdateConfig().Order == "start-first",
+		"RollbackConfig":         spec.GetRollbackConfig().Order == "stop-first",
+		"EndpointSpec":           spec.GetEndpointSpec().Ports[0].PublishedPort == 8888,
+		"PreviousSpec":           svc.HasPreviousSpec(),
+		"PreviousSpec nil-safe":  svc.PreviousSpec.GetUpdateConfig() == UpdateConfig{} && svc.PreviousSpec.TaskTemplate.GetRestartPolicy() == RestartPolicy{},
+		"Endpoint.VirtualIPs":    svc.Endpoint.VirtualIPs[0].Addr == "10.255.0.2/16",
+		"UpdateStatus":           svc.UpdateStatus.State == RolloutCompleted && svc.UpdateStatus.Message == "update completed",
+		"PreviousSpec.Endpoints": svc.PreviousSpec.GetEndpointSpec().Mode == "vip",
+	}
+	for name, ok := range tests {
+		if !ok {
+			t.Errorf("%s: unexpected decode result %#v", name, svc)
+		}
+	}
+
+	if _, err = (service{api: api}).Inspect("unknown"); err == nil {
+		t.Fatal("Inspect(unknown) should fail")
+	}
+}
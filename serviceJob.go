@@ -0,0 +1,211 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/collections"
+)
+
+// jobModeMinAPIVersion replicated-job、global-job 需要的最低 API 版本（Docker 20.10）
+const jobModeMinAPIVersion = "1.41"
+
+// JobOptions 运行一次性任务的参数
+type JobOptions struct {
+	Global        bool          // global-job：每个节点运行一次；否则为 replicated-job
+	Completions   int           // replicated-job 需要成功完成的任务数，默认 1
+	MaxConcurrent int           // replicated-job 同时运行的任务数，默认等于 Completions
+	Interval      time.Duration // 轮询间隔，默认 1s
+	CollectLogs   bool          // 结束后读取日志
+	Remove        bool          // 结束后删除服务，出错、ctx 取消或超时时也会删除（需要保留现场排查时设为 false）
+}
+
+// JobResult 一次性任务的执行结果
+type JobResult struct {
+	ServiceID   string                         // 服务ID
+	ServiceName string                         // 服务名称
+	Total       int                            // 需要成功完成的任务数
+	Succeeded   int                            // 成功完成（complete）的任务数
+	Failed      int                            // 失败（failed、rejected）的任务数
+	FailedTasks []RolloutTaskError             // 失败的任务
+	Success     bool                           // 是否全部成功完成
+	Fallback    bool                           // daemon 不支持 job 模式，使用 restart-condition=none 的副本服务代替
+	Removed     bool                           // 是否已删除服务
+	Logs        collections.List[ServiceLogVO] // 任务日志（CollectLogs=true 时）
+	StartedAt   time.Time                      // 开始时间
+	CompletedAt time.Time                      // 结束时间
+}
+
+// RunJob 以 replicated-job / global-job 模式创建服务，等待执行结束，可选读取日志、删除服务
+// 未设置重启策略时默认 restart-condition=none，失败的任务不会重试
+func (receiver service) RunJob(ctx context.Context, spec ServiceSpec, options JobOptions) (result JobResult, err error) {
+	result = JobResult{ServiceName: spec.Name, StartedAt: time.Now()}
+	if options.Completions <= 0 {
+		options.Completions = 1
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = options.Completions
+	}
+	if options.Interval <= 0 {
+		options.Interval = defaultRolloutInterval
+	}
+	if spec.TaskTemplate.RestartPolicy == nil {
+		spec.TaskTemplate.RestartPolicy = &RestartPolicy{Condition: "none"}
+	}
+	spec.UpdateConfig = nil // job 不支持滚动更新配置
+
+	// 1. 旧版本 daemon 使用 restart-condition=none 的副本服务/全局服务代替
+	version, err := receiver.api.apiVersion(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Fallback = compareAPIVersion(version, jobModeMinAPIVersion) < 0
+	switch {
+	case result.Fallback && options.Global:
+		spec.Mode = ServiceMode{Global: &struct{}{}}
+		spec.TaskTemplate.RestartPolicy = &RestartPolicy{Condition: "none"}
+	case result.Fallback:
+		spec.Mode = ServiceMode{Replicated: ReplicatedService{Replicas: options.Completions}}
+		spec.TaskTemplate.RestartPolicy = &RestartPolicy{Condition: "none"}
+	case options.Global:
+		spec.Mode = ServiceMode{GlobalJob: &struct{}{}}
+	default:
+		spec.Mode = ServiceMode{ReplicatedJob: &ReplicatedJob{MaxConcurrent: options.MaxConcurrent, TotalCompletions: options.Completions}}
+	}
+
+	// 2. 创建服务
+	created, err := receiver.CreateSpec(spec)
+	if err != nil {
+		return result, err
+	}
+	result.ServiceID = created.ID
+	if options.Remove {
+		// 任何情况下结束都删除服务（Delete 不受 ctx 取消的影响）
+		defer func() {
+			if deleteErr := receiver.Delete(created.ID); deleteErr == nil {
+				result.Removed = true
+			} else if err == nil {
+				err = deleteErr
+			}
+		}()
+	}
+
+	// 3. 等待执行结束
+	if err = receiver.waitJob(ctx, created.ID, result.Fallback, options, &result); err != nil {
+		return result, err
+	}
+	result.CompletedAt = time.Now()
+
+	// 4. 读取日志
+	if options.CollectLogs {
+		result.Logs, err = receiver.LogsContext(ctx, created.ID, ServiceLogOptions{Timestamps: true})
+	}
+	return result, err
+}
+
+// waitJob 轮询任务状态，直到成功完成的任务数达到要求，或者剩余的任务数不足以达到要求
+// job 模式只统计服务当前 JobStatus.JobIteration 的任务
+func (receiver service) waitJob(ctx context.Context, serviceId string, fallback bool, options JobOptions, result *JobResult) error {
+	for {
+		iteration := -1
+		if !fallback {
+			svc, err := UnixRequestDecode[ServiceInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services/"+serviceId), nil, nil)
+			if err != nil {
+				return err
+			}
+			if svc.JobStatus != nil {
+				iteration = svc.JobStatus.JobIteration.Index
+			}
+		}
+		tasks, err := receiver.serviceTasks(ctx, serviceId)
+		if err != nil {
+			return err
+		}
+
+		if jobProgress(tasks, iteration, options, result) {
+			result.Success = result.Total > 0 && result.Succeeded >= result.Total
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(options.Interval):
+		}
+	}
+}
+
+// jobProgress 统计任务的执行情况，返回是否已结束
+// replicated：成功完成 Completions 个任务，或者所有任务都已结束且成功+失败的数量达到 Completions（失败的任务不会重试）
+// MaxConcurrent < Completions 时，分批之间的间隙成功+失败的数量不足 Completions，会继续等待下一批
+// global：每个节点一个任务（排除被关闭的任务），任务已创建且都已结束
+func jobProgress(tasks []ServiceIdInspectJson, iteration int, options JobOptions, result *JobResult) bool {
+	active := 0
+	nodes := map[string]bool{}
+	result.Succeeded, result.Failed, result.FailedTasks = 0, 0, nil
+	for _, item := range tasks {
+		if iteration >= 0 && item.JobIteration != nil && item.JobIteration.Index != iteration {
+			continue
+		}
+		switch item.Status.State {
+		case "shutdown", "orphaned", "remove":
+			continue
+		case "complete":
+			result.Succeeded++
+		case "failed", "rejected":
+			result.Failed++
+			result.FailedTasks = append(result.FailedTasks, RolloutTaskError{
+				TaskId:   item.ID,
+				Slot:     item.Slot,
+				NodeID:   item.NodeID,
+				State:    item.Status.State,
+				Error:    item.Status.Err,
+				ExitCode: item.Status.ContainerStatus.ExitCode,
+			})
+		default:
+			active++
+		}
+		if item.NodeID != "" {
+			nodes[item.NodeID] = true
+		}
+	}
+
+	if options.Global {
+		result.Total = len(nodes)
+		return result.Total > 0 && active == 0 && result.Succeeded+result.Failed >= result.Total
+	}
+	result.Total = options.Completions
+	return result.Succeeded >= result.Total || (active == 0 && result.Succeeded+result.Failed >= result.Total)
+}
+
+// apiVersion daemon 的 API 版本 1.43
+func (receiver *dockerAPI) apiVersion(ctx context.Context) (string, error) {
+	version, err := UnixRequestDecode[struct {
+		ApiVersion string `json:"ApiVersion"`
+	}](ctx, receiver.httpClient, http.MethodGet, receiver.URL("/version"), nil, nil)
+	return version.ApiVersion, err
+}
+
+// compareAPIVersion 比较 API 版本号，a<b 返回 -1，相等返回 0，a>b 返回 1
+func compareAPIVersion(a, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(partsB[i])
+		}
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCompareAPIVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.41", "1.41", 0},
+		{"1.40", "1.41", -1},
+		{"1.43", "1.41", 1},
+		{"1.9", "1.41", -1},
+		{"1.100", "1.41", 1},
+		{"2.0", "1.41", 1},
+		{"1.41.0", "1.41", 0},
+		{"1.41.1", "1.41", 1},
+		{"1", "1.41", -1},
+		{"", "1.41", -1},
+	}
+	for _, test := range tests {
+		if got := compareAPIVersion(test.a, test.b); got != test.want {
+			t.Errorf("compareAPIVersion(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func newJobTask(node string, state string, iteration int) ServiceIdInspectJson {
+	task := ServiceIdInspectJson{NodeID: node}
+	task.Status.State = state
+	if iteration >= 0 {
+		task.JobIteration = &struct {
+			Index int `json:"Index"`
+		}{Index: iteration}
+	}
+	return task
+}
+
+func TestJobProgress(t *testing.T) {
+	replicated := JobOptions{Completions: 3, MaxConcurrent: 1}
+	global := JobOptions{Global: true}
+	tests := []struct {
+		name      string
+		tasks     []ServiceIdInspectJson
+		iteration int
+		options   JobOptions
+		finished  bool
+		succeeded int
+		failed    int
+		total     int
+	}{
+		{"not created", nil, 0, replicated, false, 0, 0, 3},
+		{"between slots", []ServiceIdInspectJson{newJobTask("n1", "complete", 0)}, 0, replicated, false, 1, 0, 3},
+		{"second slot running", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("n1", "running", 0)}, 0, replicated, false, 1, 0, 3},
+		{"all completed", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("n1", "complete", 0), newJobTask("n2", "complete", 0)}, 0, replicated, true, 3, 0, 3},
+		{"failed slot", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("n1", "failed", 0), newJobTask("n2", "complete", 0)}, 0, replicated, true, 2, 1, 3},
+		{"previous iteration ignored", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("n1", "complete", 0), newJobTask("n1", "complete", 1)}, 1, replicated, false, 1, 0, 3},
+		{"fallback without iteration", []ServiceIdInspectJson{newJobTask("n1", "complete", -1), newJobTask("n1", "complete", -1), newJobTask("n1", "complete", -1)}, -1, replicated, true, 3, 0, 3},
+		{"global pending", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("", "pending", 0)}, 0, global, false, 1, 0, 1},
+		{"global done", []ServiceIdInspectJson{newJobTask("n1", "complete", 0), newJobTask("n2", "rejected", 0), newJobTask("n3", "shutdown", 0)}, 0, global, true, 1, 1, 2},
+	}
+	for _, test := range tests {
+		var result JobResult
+		finished := jobProgress(test.tasks, test.iteration, test.options, &result)
+		if finished != test.finished || result.Succeeded != test.succeeded || result.Failed != test.failed || result.Total != test.total {
+			t.Errorf("%s: jobProgress() = %v, %d/%d failed %d, want %v, %d/%d failed %d", test.name, finished, result.Succeeded, result.Total, result.Failed, test.finished, test.succeeded, test.total, test.failed)
+		}
+	}
+}
+
+func TestRunJobRemovesServiceOnError(t *testing.T) {
+	deleted := false
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/version":
+			json.NewEncoder(w).Encode(map[string]string{"ApiVersion": "1.43"})
+		case r.URL.Path == "/services/create":
+			var spec ServiceSpec
+			json.NewDecoder(r.Body).Decode(&spec)
+			if spec.Mode.ReplicatedJob == nil || spec.Mode.ReplicatedJob.TotalCompletions != 2 || spec.TaskTemplate.GetRestartPolicy().Condition != "none" {
+				t.Errorf("unexpected spec %#v", spec)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"ID": "job1"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/services/job1":
+			deleted = true
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "daemon unavailable"})
+		}
+	})
+
+	spec, _ := NewServiceSpec("migrate", "farseer/migrate:v1").Build()
+	result, err := service{api: api}.RunJob(context.Background(), spec, JobOptions{Completions: 2, Remove: true})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !deleted || !result.Removed {
+		t.Fatalf("RunJob() = %#v, %v, deleted = %v", result, err, deleted)
+	}
+}
@@ -0,0 +1,315 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/farseer-go/collections"
+)
+
+// ServiceLogOptions 读取服务、任务日志的参数
+type ServiceLogOptions struct {
+	Follow     bool      // 持续读取新日志，直到 ctx 取消
+	Since      time.Time // 只读取此时间之后的日志
+	Tail       string    // 只读取最后 N 行："100"、"0"（不读取历史日志），为空或 ServiceLogTailAll 时读取全部
+	Timestamps bool      // 读取日志的时间
+	Stdout     bool      // 读取标准输出（Stdout、Stderr 都为 false 时读取两者）
+	Stderr     bool      // 读取标准错误
+}
+
+// ServiceLogTailAll 读取全部日志
+const ServiceLogTailAll = "all"
+
+// ServiceLogEntry 一行日志
+type ServiceLogEntry struct {
+	ServiceID   string    // 服务ID
+	ServiceName string    // 服务名称
+	TaskId      string    // 任务ID
+	TaskName    string    // 任务名称 fops.1（全局服务为 fops.{节点ID}）
+	Slot        int       // 副本序号
+	NodeID      string    // 节点ID
+	NodeName    string    // 节点名称
+	Stream      string    // stdout stderr
+	Timestamp   time.Time // Timestamps=true 时有值
+	Line        string    // 日志内容
+}
+
+// StreamLogs 读取服务的日志（/services/{id}/logs），每读取一行回调一次；Follow 时持续读取直到 ctx 取消
+func (receiver service) StreamLogs(ctx context.Context, serviceName string, options ServiceLogOptions, onEntry func(entry ServiceLogEntry)) error {
+	resolver := newLogResolver(ctx, receiver.api)
+	svc, err := resolver.service(serviceName)
+	if err != nil {
+		return err
+	}
+
+	// 预先读取服务的任务，follow 过程中出现的新任务再单独查询
+	tasks, err := receiver.serviceTasks(ctx, svc.ID)
+	if err != nil {
+		return err
+	}
+	for _, item := range tasks {
+		resolver.tasks[item.ID] = item
+	}
+
+	// curl --unix-socket /var/run/docker.sock "http://localhost/services/fops/logs?stdout=true&stderr=true&details=true&tail=100"
+	return receiver.api.streamSwarmLogs(ctx, fmt.Sprintf("/services/%s/logs", svc.ID), svc.Spec.TaskTemplate.ContainerSpec.TTY, options, resolver, onEntry)
+}
+
+// LogsContext 读取服务的日志，按任务分组（不支持 Follow）
+func (receiver service) LogsContext(ctx context.Context, serviceName string, options ServiceLogOptions) (collections.List[ServiceLogVO], error) {
+	options.Follow = false
+	lst := collections.NewList[ServiceLogVO]()
+	err := receiver.StreamLogs(ctx, serviceName, options, func(entry ServiceLogEntry) {
+		appendServiceLog(&lst, entry)
+	})
+	return lst, err
+}
+
+// StreamLogs 读取任务的日志（/tasks/{id}/logs），每读取一行回调一次；Follow 时持续读取直到 ctx 取消
+func (receiver task) StreamLogs(ctx context.Context, taskId string, options ServiceLogOptions, onEntry func(entry ServiceLogEntry)) error {
+	resolver := newLogResolver(ctx, receiver.api)
+	item, err := resolver.task(taskId)
+	if err != nil {
+		return err
+	}
+
+	// curl --unix-socket /var/run/docker.sock "http://localhost/tasks/kbo9xu9qtxw1b69r02tt57bvh/logs?stdout=true&stderr=true&details=true"
+	return receiver.api.streamSwarmLogs(ctx, fmt.Sprintf("/tasks/%s/logs", item.ID), item.Spec.ContainerSpec.TTY, options, resolver, onEntry)
+}
+
+// Logs 读取任务的日志（不支持 Follow）
+func (receiver task) Logs(ctx context.Context, taskId string, options ServiceLogOptions) (ServiceLogVO, error) {
+	options.Follow = false
+	lst := collections.NewList[ServiceLogVO]()
+	err := receiver.StreamLogs(ctx, taskId, options, func(entry ServiceLogEntry) {
+		appendServiceLog(&lst, entry)
+	})
+	return lst.First(), err
+}
+
+// appendServiceLog 将日志追加到所属任务的分组
+func appendServiceLog(lst *collections.List[ServiceLogVO], entry ServiceLogEntry) {
+	if curTask := lst.Find(func(item *ServiceLogVO) bool {
+		return item.TaskId == entry.TaskId
+	}); curTask != nil {
+		curTask.Logs.Add(entry.Line)
+		curTask.Entries.Add(entry)
+		return
+	}
+	lst.Add(ServiceLogVO{
+		ContainerId: truncateTaskId(entry.TaskId),
+		ServiceName: entry.TaskName,
+		NodeName:    entry.NodeName,
+		TaskId:      entry.TaskId,
+		Slot:        entry.Slot,
+		NodeID:      entry.NodeID,
+		Logs:        collections.NewList(entry.Line),
+		Entries:     collections.NewList(entry),
+	})
+}
+
+// truncateTaskId 截断为 12 位，与 docker service logs 输出的 fops.1.l71hvj98bsqx 一致
+func truncateTaskId(taskId string) string {
+	if len(taskId) > 12 {
+		return taskId[:12]
+	}
+	return taskId
+}
+
+// streamSwarmLogs 读取 swarm 日志流：TTY 时为原始文本，否则为 8 字节头 + 载荷的多路复用格式
+func (receiver *dockerAPI) streamSwarmLogs(ctx context.Context, apiPath string, tty bool, options ServiceLogOptions, resolver *logResolver, onEntry func(entry ServiceLogEntry)) error {
+	query := url.Values{}
+	query.Set("details", "true") // 需要 details 才能得到节点、任务ID
+	query.Set("stdout", fmt.Sprintf("%t", options.Stdout || !options.Stderr))
+	query.Set("stderr", fmt.Sprintf("%t", options.Stderr || !options.Stdout))
+	query.Set("follow", fmt.Sprintf("%t", options.Follow))
+	query.Set("timestamps", fmt.Sprintf("%t", options.Timestamps))
+	if !options.Since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d.%09d", options.Since.Unix(), options.Since.Nanosecond()))
+	}
+	if options.Tail != "" {
+		query.Set("tail", options.Tail)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, receiver.URL(apiPath+"?"+query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := receiver.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp)
+	}
+
+	emit := func(stream string, payload string) {
+		for _, line := range strings.Split(strings.TrimRight(payload, "\n"), "\n") {
+			entry := parseServiceLogLine(strings.TrimRight(line, "\r"), options.Timestamps)
+			entry.Stream = stream
+			resolver.resolve(&entry)
+			onEntry(entry)
+		}
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if tty {
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				emit("stdout", line)
+			}
+			if err != nil {
+				return streamEndError(ctx, err)
+			}
+		}
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err = io.ReadFull(reader, header); err != nil {
+			return streamEndError(ctx, err)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:8]))
+		if _, err = io.ReadFull(reader, payload); err != nil {
+			return streamEndError(ctx, err)
+		}
+
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+		emit(stream, string(payload))
+	}
+}
+
+// streamEndError 读取结束：EOF 为正常结束，ctx 取消时返回 ctx 的错误
+func streamEndError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// parseServiceLogLine 解析 [时间 ]details 日志内容
+// details：com.docker.swarm.node.id=xxx,com.docker.swarm.service.id=xxx,com.docker.swarm.task.id=xxx（key、value 经过 url 编码）
+func parseServiceLogLine(line string, timestamps bool) ServiceLogEntry {
+	var entry ServiceLogEntry
+	if timestamps {
+		if value, rest, found := strings.Cut(line, " "); found {
+			if timestamp, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				entry.Timestamp, line = timestamp, rest
+			}
+		}
+	}
+
+	details, rest, _ := strings.Cut(line, " ")
+	entry.Line = rest
+	for _, pair := range strings.Split(details, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			// 不是 details，整行都是日志内容
+			entry.Line = line
+			return entry
+		}
+		key, _ = url.QueryUnescape(key)
+		value, _ = url.QueryUnescape(value)
+		switch key {
+		case "com.docker.swarm.node.id":
+			entry.NodeID = value
+		case "com.docker.swarm.service.id":
+			entry.ServiceID = value
+		case "com.docker.swarm.task.id":
+			entry.TaskId = value
+		}
+	}
+	return entry
+}
+
+// logResolver 根据ID补全服务名称、任务序号、节点名称，查询结果会缓存
+type logResolver struct {
+	ctx      context.Context
+	api      *dockerAPI
+	services map[string]ServiceInspectJson
+	tasks    map[string]ServiceIdInspectJson
+	nodes    map[string]string // 节点ID -> 节点名称
+}
+
+func newLogResolver(ctx context.Context, api *dockerAPI) *logResolver {
+	resolver := &logResolver{
+		ctx:      ctx,
+		api:      api,
+		services: map[string]ServiceInspectJson{},
+		tasks:    map[string]ServiceIdInspectJson{},
+		nodes:    map[string]string{},
+	}
+	node{api: api}.List().Foreach(func(item *DockerNodeVO) {
+		resolver.nodes[item.ID] = item.Description.Hostname
+	})
+	return resolver
+}
+
+func (receiver *logResolver) service(serviceIdOrName string) (ServiceInspectJson, error) {
+	if svc, exists := receiver.services[serviceIdOrName]; exists {
+		return svc, nil
+	}
+	svc, err := UnixRequestDecode[ServiceInspectJson](receiver.ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services/"+serviceIdOrName), nil, nil)
+	if err != nil {
+		return svc, err
+	}
+	receiver.services[svc.ID] = svc
+	return svc, nil
+}
+
+func (receiver *logResolver) task(taskId string) (ServiceIdInspectJson, error) {
+	if item, exists := receiver.tasks[taskId]; exists {
+		return item, nil
+	}
+	item, err := UnixRequestDecode[ServiceIdInspectJson](receiver.ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/tasks/"+taskId), nil, nil)
+	if err != nil {
+		return item, err
+	}
+	receiver.tasks[item.ID] = item
+	return item, nil
+}
+
+func (receiver *logResolver) resolve(entry *ServiceLogEntry) {
+	entry.NodeName = receiver.nodes[entry.NodeID]
+	if entry.TaskId != "" {
+		item, err := receiver.task(entry.TaskId)
+		if err != nil {
+			// 任务已被删除：缓存空结果，避免每行日志都重复查询
+			receiver.tasks[entry.TaskId] = item
+		}
+		entry.Slot = item.Slot
+		if entry.ServiceID == "" {
+			entry.ServiceID = item.ServiceID
+		}
+	}
+	if entry.ServiceID != "" {
+		svc, err := receiver.service(entry.ServiceID)
+		if err != nil {
+			receiver.services[entry.ServiceID] = svc
+		}
+		entry.ServiceName = svc.Spec.Name
+	}
+
+	// 与 docker service logs 的名称保持一致
+	if entry.Slot > 0 {
+		entry.TaskName = fmt.Sprintf("%s.%d", entry.ServiceName, entry.Slot)
+	} else {
+		entry.TaskName = fmt.Sprintf("%s.%s", entry.ServiceName, entry.NodeID)
+	}
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseServiceLogLine(t *testing.T) {
+	timestamp := time.Date(2024, 3, 4, 17, 57, 2, 672616000, time.UTC)
+	tests := []struct {
+		name       string
+		line       string
+		timestamps bool
+		want       ServiceLogEntry
+	}{
+		{"details", "com.docker.swarm.node.id=n1,com.docker.swarm.service.id=s1,com.docker.swarm.task.id=t1 Initialization completed", false, ServiceLogEntry{NodeID: "n1", ServiceID: "s1", TaskId: "t1", Line: "Initialization completed"}},
+		{"timestamp and details", "2024-03-04T17:57:02.672616Z com.docker.swarm.node.id=n1,com.docker.swarm.task.id=t1 ok", true, ServiceLogEntry{NodeID: "n1", TaskId: "t1", Timestamp: timestamp, Line: "ok"}},
+		{"escaped details", "com.docker.swarm.task.id=t%2C1,tag=a%3Db hello world", false, ServiceLogEntry{TaskId: "t,1", Line: "hello world"}},
+		{"empty line", "com.docker.swarm.task.id=t1 ", false, ServiceLogEntry{TaskId: "t1"}},
+		{"no details", "plain log line", false, ServiceLogEntry{Line: "plain log line"}},
+		{"invalid timestamp", "yesterday plain", true, ServiceLogEntry{Line: "yesterday plain"}},
+	}
+	for _, test := range tests {
+		if got := parseServiceLogLine(test.line, test.timestamps); got != test.want {
+			t.Errorf("%s: parseServiceLogLine() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestServiceLogsTail(t *testing.T) {
+	var tail string
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/fops":
+			json.NewEncoder(w).Encode(map[string]any{"ID": "svc1", "Spec": map[string]any{"Name": "fops"}})
+		case "/tasks":
+			json.NewEncoder(w).Encode([]map[string]any{{"ID": "l71hvj98bsqx0123456789abc", "ServiceID": "svc1", "Slot": 1, "NodeID": "n1"}})
+		case "/nodes":
+			json.NewEncoder(w).Encode([]map[string]any{{"ID": "n1", "Description": map[string]string{"Hostname": "master"}}})
+		case "/services/svc1/logs":
+			tail = r.URL.Query().Get("tail")
+			payload := []byte("com.docker.swarm.node.id=n1,com.docker.swarm.service.id=svc1,com.docker.swarm.task.id=l71hvj98bsqx0123456789abc started\n")
+			header := make([]byte, 8)
+			header[0] = 1
+			binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+			w.Write(append(header, payload...))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	client := service{api: api}
+
+	tests := map[int]string{0: "0", 100: "100", -1: ServiceLogTailAll}
+	for tailCount, want := range tests {
+		logs, err := client.Logs("fops", tailCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tail != want {
+			t.Errorf("Logs(fops, %d) tail = %q, want %q", tailCount, tail, want)
+		}
+		item := logs.First()
+		if item.ContainerId != "l71hvj98bsqx" || item.TaskId != "l71hvj98bsqx0123456789abc" || item.ServiceName != "fops.1" || item.NodeName != "master" || item.Logs.First() != "started" {
+			t.Errorf("Logs(fops, %d) = %#v", tailCount, item)
+		}
+	}
+}
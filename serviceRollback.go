@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// Rollback 回滚到上一次的配置 PreviousSpec（POST /services/{id}/update?rollback=previous）
+func (receiver service) Rollback(serviceName string) (ServiceUpdateResult, error) {
+	return receiver.RollbackContext(context.Background(), serviceName)
+}
+
+// RollbackContext 同 Rollback（支持 ctx 控制超时/取消）
+func (receiver service) RollbackContext(ctx context.Context, serviceName string) (ServiceUpdateResult, error) {
+	query := url.Values{}
+	query.Set("rollback", "previous")
+	return receiver.update(ctx, serviceName, query, func(current ServiceInspectJson) (ServiceSpec, error) {
+		if !current.HasPreviousSpec() {
+			return current.Spec, fmt.Errorf("service %s has no previous spec to roll back to", serviceName)
+		}
+		// 与 CLI 一致：提交当前配置，由 daemon 替换为 PreviousSpec
+		return current.Spec, nil
+	})
+}
+
+// RollbackTo 回滚到指定的配置（例如自己保存的历史版本），服务名称保持不变
+func (receiver service) RollbackTo(ctx context.Context, serviceName string, spec ServiceSpec) (ServiceUpdateResult, error) {
+	return receiver.update(ctx, serviceName, nil, func(current ServiceInspectJson) (ServiceSpec, error) {
+		if spec.Name != "" && spec.Name != current.Spec.Name {
+			return spec, fmt.Errorf("spec name %s does not match service %s", spec.Name, current.Spec.Name)
+		}
+		spec.Name = current.Spec.Name
+		return spec, nil
+	})
+}
+
+// errServiceSpecUnchanged 修改后的配置与当前配置相同，不需要提交
+var errServiceSpecUnchanged = errors.New("service spec unchanged")
+
+// ImageRolloutOptions 更新镜像并监控滚动更新的参数
+type ImageRolloutOptions struct {
+	UpdateDelay  int                 // 滚动更新时的时间间隔（秒）
+	AutoRollback bool                // 更新失败（paused）时自动回滚到上一个版本
+	Watch        RolloutWatchOptions // 监控参数
+}
+
+// RolloutImage 更新镜像版本并等待滚动更新结束，失败时按配置自动回滚
+func (receiver service) RolloutImage(ctx context.Context, serviceName string, dockerImages string, options ImageRolloutOptions, onProgress func(event RolloutEvent)) (RolloutResult, error) {
+	result := RolloutResult{RolloutEvent: RolloutEvent{ServiceName: serviceName}}
+
+	// 1. 提交更新，记录提交前服务的 UpdatedAt（daemon 的时间），只关注之后开始的更新
+	image, warning := receiver.pinImageDigest(ctx, dockerImages)
+	updateResult, err := receiver.update(ctx, serviceName, nil, func(current ServiceInspectJson) (ServiceSpec, error) {
+		spec := current.Spec
+		setServiceImage(&spec, image, options.UpdateDelay)
+		if reflect.DeepEqual(spec, current.Spec) {
+			return spec, errServiceSpecUnchanged
+		}
+		options.Watch.Since = current.UpdatedAt
+		return spec, nil
+	})
+	warnings := withWarning(updateResult, warning).Warnings
+	result.Warnings = warnings
+	if errors.Is(err, errServiceSpecUnchanged) {
+		// 配置没有变化，daemon 不会滚动更新
+		result.State, result.Success = RolloutCompleted, true
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// 2. 等待更新结束
+	result, err = receiver.WatchRollout(ctx, serviceName, options.Watch, onProgress)
+	result.Warnings = warnings
+	if err != nil || result.Success || !options.AutoRollback || result.State != RolloutPaused {
+		return result, err
+	}
+
+	// 3. 更新失败，回滚并等待回滚结束
+	current, _, err := receiver.inspectSpec(ctx, serviceName)
+	if err != nil {
+		return result, err
+	}
+	options.Watch.Since = current.UpdatedAt
+	if _, err = receiver.RollbackContext(ctx, serviceName); err != nil {
+		return result, fmt.Errorf("rollout failed (%s), rollback failed: %w", result.Reason, err)
+	}
+
+	rollbackResult, err := receiver.WatchRollout(ctx, serviceName, options.Watch, onProgress)
+	rollbackResult.Success = false
+	rollbackResult.RolledBack = rollbackResult.State == RolloutRollbackCompleted
+	rollbackResult.Reason = result.Reason
+	rollbackResult.Warnings = warnings
+	return rollbackResult, err
+}
@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRollbackDaemon 模拟滚动更新失败（paused）以及 rollback=previous 的服务
+type fakeRollbackDaemon struct {
+	mu           sync.Mutex
+	now          time.Time
+	version      int
+	spec         ServiceSpec
+	previousSpec *ServiceSpec
+	updatedAt    time.Time
+	updateStatus map[string]any
+	queries      []string      // 每次提交更新时的 query
+	bodies       []ServiceSpec // 每次提交更新时的配置
+	failRollout  bool          // 更新后暂停（任务启动失败）
+}
+
+func newFakeRollbackDaemon(spec ServiceSpec, previousSpec *ServiceSpec) *fakeRollbackDaemon {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &fakeRollbackDaemon{now: now, version: 10, spec: spec, previousSpec: previousSpec, updatedAt: now}
+}
+
+func (receiver *fakeRollbackDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/distribution/"):
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"authentication required"}`))
+	case r.URL.Path == "/tasks":
+		w.Write([]byte("[]"))
+	case r.Method == http.MethodGet && r.URL.Path == "/services/fops":
+		svc := map[string]any{"ID": "svc1", "Version": map[string]int{"Index": receiver.version}, "UpdatedAt": receiver.updatedAt, "Spec": receiver.spec}
+		if receiver.previousSpec != nil {
+			svc["PreviousSpec"] = receiver.previousSpec
+		}
+		if receiver.updateStatus != nil {
+			svc["UpdateStatus"] = receiver.updateStatus
+		}
+		json.NewEncoder(w).Encode(svc)
+	case r.Method == http.MethodPost && r.URL.Path == "/services/svc1/update":
+		var spec ServiceSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		receiver.queries = append(receiver.queries, r.URL.RawQuery)
+		receiver.bodies = append(receiver.bodies, spec)
+
+		receiver.now = receiver.now.Add(time.Minute)
+		receiver.version++
+		receiver.updatedAt = receiver.now
+		if r.URL.Query().Get("rollback") == "previous" {
+			current := receiver.spec
+			receiver.spec, receiver.previousSpec = *receiver.previousSpec, &current
+			receiver.updateStatus = map[string]any{"State": RolloutRollbackCompleted, "StartedAt": receiver.now, "Message": "rollback completed"}
+		} else {
+			current := receiver.spec
+			receiver.spec, receiver.previousSpec = spec, &current
+			if receiver.failRollout {
+				receiver.updateStatus = map[string]any{"State": RolloutPaused, "StartedAt": receiver.now, "Message": "update paused due to failure or early termination of task x"}
+			} else {
+				receiver.updateStatus = map[string]any{"State": RolloutCompleted, "StartedAt": receiver.now}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"not found"}`))
+	}
+}
+
+func newRollbackSpec(image string) ServiceSpec {
+	return ServiceSpec{Name: "fops", TaskTemplate: TaskSpec{ContainerSpec: ContainerSpec{Image: image}}, Mode: ServiceMode{Replicated: ReplicatedService{Replicas: 0}}}
+}
+
+func TestServiceRollback(t *testing.T) {
+	previous := newRollbackSpec("farseer/fops:v1")
+	daemon := newFakeRollbackDaemon(newRollbackSpec("farseer/fops:v2"), &previous)
+	client := service{api: newTestDockerAPI(t, daemon.ServeHTTP)}
+
+	// 与 CLI 一致：提交当前配置并带上 rollback=previous
+	result, err := client.Rollback("fops")
+	if err != nil || result.Version != 10 {
+		t.Fatalf("Rollback() = %+v, %v", result, err)
+	}
+	if len(daemon.queries) != 1 || !strings.Contains(daemon.queries[0], "rollback=previous") || !strings.Contains(daemon.queries[0], "version=10") {
+		t.Fatalf("queries = %v", daemon.queries)
+	}
+	if daemon.bodies[0].TaskTemplate.ContainerSpec.Image != "farseer/fops:v2" || daemon.spec.TaskTemplate.ContainerSpec.Image != "farseer/fops:v1" {
+		t.Fatalf("submitted %s, current %s", daemon.bodies[0].TaskTemplate.ContainerSpec.Image, daemon.spec.TaskTemplate.ContainerSpec.Image)
+	}
+
+	// 没有 PreviousSpec 时不提交
+	daemon.previousSpec = nil
+	if _, err = client.Rollback("fops"); err == nil || len(daemon.queries) != 1 {
+		t.Fatalf("Rollback() without previous spec = %v, queries %v", err, daemon.queries)
+	}
+}
+
+func TestServiceRollbackTo(t *testing.T) {
+	daemon := newFakeRollbackDaemon(newRollbackSpec("farseer/fops:v3"), nil)
+	client := service{api: newTestDockerAPI(t, daemon.ServeHTTP)}
+
+	// 名称为空时使用服务名称，不带 rollback 参数
+	spec := newRollbackSpec("farseer/fops:v1")
+	spec.Name = ""
+	if _, err := client.RollbackTo(context.Background(), "fops", spec); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(daemon.queries[0], "rollback") || daemon.bodies[0].Name != "fops" || daemon.spec.TaskTemplate.ContainerSpec.Image != "farseer/fops:v1" {
+		t.Fatalf("query = %s, spec = %+v", daemon.queries[0], daemon.bodies[0])
+	}
+
+	spec.Name = "other"
+	if _, err := client.RollbackTo(context.Background(), "fops", spec); err == nil || len(daemon.queries) != 1 {
+		t.Fatalf("RollbackTo() with other name = %v, queries %v", err, daemon.queries)
+	}
+}
+
+func TestRolloutImageAutoRollback(t *testing.T) {
+	daemon := newFakeRollbackDaemon(newRollbackSpec("farseer/fops:v1"), nil)
+	daemon.failRollout = true
+	client := service{api: newTestDockerAPI(t, daemon.ServeHTTP)}
+
+	var states []string
+	options := ImageRolloutOptions{AutoRollback: true, Watch: RolloutWatchOptions{Interval: 10 * time.Millisecond}}
+	result, err := client.RolloutImage(context.Background(), "fops", "farseer/fops:v2", options, func(event RolloutEvent) {
+		states = append(states, event.State)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 更新暂停后自动回滚：保留失败原因，回滚完成
+	if result.Success || !result.RolledBack || result.State != RolloutRollbackCompleted || !strings.Contains(result.Reason, "update paused") {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(daemon.queries) != 2 || strings.Contains(daemon.queries[0], "rollback") || !strings.Contains(daemon.queries[1], "rollback=previous") {
+		t.Fatalf("queries = %v", daemon.queries)
+	}
+	if daemon.spec.TaskTemplate.ContainerSpec.Image != "farseer/fops:v1" || strings.Join(states, ",") != RolloutPaused+","+RolloutRollbackCompleted {
+		t.Fatalf("image = %s, states = %v", daemon.spec.TaskTemplate.ContainerSpec.Image, states)
+	}
+	// 无法解析 digest 时使用原镜像，并返回警告
+	if daemon.bodies[0].TaskTemplate.ContainerSpec.Image != "farseer/fops:v2" || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "could not be accessed") {
+		t.Fatalf("image = %s, warnings = %v", daemon.bodies[0].TaskTemplate.ContainerSpec.Image, result.Warnings)
+	}
+}
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// 滚动更新状态 UpdateStatus.State
+const (
+	RolloutUpdating          = "updating"
+	RolloutPaused            = "paused"
+	RolloutCompleted         = "completed"
+	RolloutRollbackStarted   = "rollback_started"
+	RolloutRollbackPaused    = "rollback_paused"
+	RolloutRollbackCompleted = "rollback_completed"
+)
+
+const defaultRolloutInterval = time.Second
+
+// RolloutWatchOptions 监控滚动更新的参数
+type RolloutWatchOptions struct {
+	Interval time.Duration // 轮询间隔，默认 1s
+	Since    time.Time     // 只关注此时间之后开始的更新：传入提交更新前服务的 UpdatedAt（daemon 的时间，不要使用本地时间），为空时以当前的更新状态为准
+}
+
+// RolloutTaskError 更新过程中失败的任务
+type RolloutTaskError struct {
+	TaskId   string // 任务ID
+	Slot     int    // 副本序号
+	NodeID   string // 节点ID
+	State    string // failed rejected
+	Error    string // 错误信息
+	ExitCode int    // 容器退出码
+}
+
+// RolloutEvent 滚动更新的进度
+type RolloutEvent struct {
+	ServiceName  string             // 服务名称
+	State        string             // 更新状态 updating paused completed rollback_started rollback_paused rollback_completed
+	Message      string             // daemon 返回的状态说明
+	TasksUpdated int                // 已运行新配置的任务数
+	TasksTotal   int                // 期望的任务数
+	FailedTasks  []RolloutTaskError // 本次更新中失败的任务
+}
+
+// RolloutResult 滚动更新的最终结果
+type RolloutResult struct {
+	RolloutEvent
+	Success     bool      // 是否更新成功（completed）
+	RolledBack  bool      // 是否已自动回滚
+	Reason      string    // 失败原因
+	StartedAt   time.Time // 开始时间
+	CompletedAt time.Time // 完成时间
+	Warnings    []string  // 提交更新时的警告信息，如：镜像无法解析digest
+}
+
+// WatchRollout 监控服务的滚动更新，直到 completed、paused、rollback_completed（或 ctx 超时）
+// onProgress 在进度变化时回调，可以为 nil
+func (receiver service) WatchRollout(ctx context.Context, serviceName string, options RolloutWatchOptions, onProgress func(event RolloutEvent)) (RolloutResult, error) {
+	if options.Interval <= 0 {
+		options.Interval = defaultRolloutInterval
+	}
+
+	result := RolloutResult{RolloutEvent: RolloutEvent{ServiceName: serviceName}}
+	lastEvent := ""
+	for {
+		svc, err := UnixRequestDecode[ServiceInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/services/%s", serviceName)), nil, nil)
+		if err != nil {
+			return result, err
+		}
+
+		tasks, err := receiver.serviceTasks(ctx, svc.ID)
+		if err != nil {
+			return result, err
+		}
+
+		// 1. 计算进度：开始更新后只统计本次更新创建的任务，不会滚动更新任务时统计所有任务
+		started := rolloutStarted(svc, options.Since)
+		since := options.Since
+		if started {
+			since = svc.UpdateStatus.StartedAt
+			result.State, result.Message = svc.UpdateStatus.State, svc.UpdateStatus.Message
+			result.StartedAt, result.CompletedAt = svc.UpdateStatus.StartedAt, svc.UpdateStatus.CompletedAt
+		} else if rolloutSkipped(svc, options.Since) {
+			since = time.Time{}
+		}
+		result.TasksUpdated, result.TasksTotal, result.FailedTasks = rolloutProgress(svc.Spec, tasks, since)
+
+		// 2. 进度变化时回调
+		if event := fmt.Sprintf("%s|%d/%d|%d|%s", result.State, result.TasksUpdated, result.TasksTotal, len(result.FailedTasks), result.Message); event != lastEvent {
+			lastEvent = event
+			if onProgress != nil {
+				onProgress(result.RolloutEvent)
+			}
+		}
+
+		// 3. 是否结束
+		if rolloutFinished(svc, options.Since, &result) {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Reason = ctx.Err().Error()
+			return result, ctx.Err()
+		case <-time.After(options.Interval):
+		}
+	}
+}
+
+// rolloutStarted UpdateStatus 是否为 since 之后开始的更新（StartedAt、since 都是 daemon 的时间）
+func rolloutStarted(svc ServiceInspectJson, since time.Time) bool {
+	return svc.UpdateStatus.State != "" && (since.IsZero() || svc.UpdateStatus.StartedAt.After(since))
+}
+
+// rolloutSkipped 服务不会滚动更新任务，daemon 也不会写入新的 UpdateStatus：
+// 新创建的服务，或者 since 之后的修改没有改变任务配置（只修改了标签、副本数、更新策略，或提交了相同的配置）
+func rolloutSkipped(svc ServiceInspectJson, since time.Time) bool {
+	if svc.UpdateStatus.State == "" && !svc.HasPreviousSpec() {
+		return true
+	}
+	return !rolloutStarted(svc, since) && svc.UpdatedAt.After(since) &&
+		reflect.DeepEqual(svc.Spec.TaskTemplate, svc.PreviousSpec.TaskTemplate) &&
+		reflect.DeepEqual(svc.Spec.EndpointSpec, svc.PreviousSpec.EndpointSpec)
+}
+
+// rolloutFinished 根据服务状态和 result 中的进度判断更新是否结束，结束时设置 State、Success、Reason
+func rolloutFinished(svc ServiceInspectJson, since time.Time, result *RolloutResult) bool {
+	switch {
+	case !rolloutStarted(svc, since):
+		// 不会滚动更新时，期望的任务都运行后即完成（全局服务在任务创建前期望数为 0）
+		if rolloutSkipped(svc, since) && result.TasksUpdated >= result.TasksTotal && (result.TasksTotal > 0 || !svc.Spec.Mode.IsGlobal()) {
+			result.State, result.Success = RolloutCompleted, true
+			return true
+		}
+		return false
+	case result.State == RolloutCompleted:
+		result.Success = true
+		return true
+	case result.State == RolloutPaused || result.State == RolloutRollbackPaused || result.State == RolloutRollbackCompleted:
+		result.Reason = result.Message
+		if result.Reason == "" && len(result.FailedTasks) > 0 {
+			result.Reason = result.FailedTasks[0].Error
+		}
+		return true
+	}
+	return false
+}
+
+// serviceTasks 获取服务的所有任务
+func (receiver service) serviceTasks(ctx context.Context, serviceId string) ([]ServiceIdInspectJson, error) {
+	filter := fmt.Sprintf(`{"service":{"%s":true}}`, serviceId)
+	tasksUrl := receiver.api.URL("/tasks?filters=" + url.QueryEscape(filter))
+	return UnixRequestDecode[[]ServiceIdInspectJson](ctx, receiver.api.httpClient, http.MethodGet, tasksUrl, nil, nil)
+}
+
+// rolloutProgress 统计 since 之后创建并运行中的任务数、期望任务数、失败的任务
+func rolloutProgress(spec ServiceSpec, tasks []ServiceIdInspectJson, since time.Time) (int, int, []RolloutTaskError) {
+	var updated, total int
+	var failed []RolloutTaskError
+	for _, task := range tasks {
+		if task.DesiredState == "running" {
+			total++
+		}
+		if task.CreatedAt.Before(since) {
+			continue
+		}
+
+		switch task.Status.State {
+		case "running":
+			if task.DesiredState == "running" {
+				updated++
+			}
+		case "failed", "rejected":
+			failed = append(failed, RolloutTaskError{
+				TaskId:   task.ID,
+				Slot:     task.Slot,
+				NodeID:   task.NodeID,
+				State:    task.Status.State,
+				Error:    task.Status.Err,
+				ExitCode: task.Status.ContainerStatus.ExitCode,
+			})
+		}
+	}
+
+	// 副本服务以 Replicas 为准，全局服务以期望运行的任务数为准
+	if !spec.Mode.IsGlobal() && !spec.Mode.IsJob() {
+		total = spec.Mode.Replicated.Replicas
+	}
+	// start-first 时新旧任务会同时运行
+	if updated > total {
+		updated = total
+	}
+	return updated, total, failed
+}
@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newRolloutTask(id string, createdAt time.Time, desiredState string, state string) ServiceIdInspectJson {
+	task := ServiceIdInspectJson{ID: id, CreatedAt: createdAt, DesiredState: desiredState}
+	task.Status.State = state
+	if state == "failed" {
+		task.Status.Err = "task: non-zero exit (1)"
+		task.Status.ContainerStatus.ExitCode = 1
+	}
+	return task
+}
+
+func TestRolloutProgress(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := since.Add(-time.Minute), since.Add(time.Minute)
+	replicated := ServiceSpec{Mode: ServiceMode{Replicated: ReplicatedService{Replicas: 2}}}
+	global := ServiceSpec{Mode: ServiceMode{Global: &struct{}{}}}
+
+	tests := []struct {
+		name          string
+		spec          ServiceSpec
+		tasks         []ServiceIdInspectJson
+		since         time.Time
+		updated       int
+		total         int
+		failed        int
+		failedMessage string
+	}{
+		{"old tasks are not updated", replicated, []ServiceIdInspectJson{newRolloutTask("a", before, "running", "running"), newRolloutTask("b", before, "running", "running")}, since, 0, 2, 0, ""},
+		{"new task running", replicated, []ServiceIdInspectJson{newRolloutTask("a", before, "shutdown", "shutdown"), newRolloutTask("b", before, "running", "running"), newRolloutTask("c", after, "running", "running")}, since, 1, 2, 0, ""},
+		{"start-first overlaps", replicated, []ServiceIdInspectJson{newRolloutTask("a", after, "running", "running"), newRolloutTask("b", after, "running", "running"), newRolloutTask("c", after, "running", "running")}, since, 2, 2, 0, ""},
+		{"failed task", replicated, []ServiceIdInspectJson{newRolloutTask("a", before, "running", "running"), newRolloutTask("b", after, "shutdown", "failed")}, since, 0, 2, 1, "task: non-zero exit (1)"},
+		{"zero since counts all tasks", replicated, []ServiceIdInspectJson{newRolloutTask("a", before, "running", "running"), newRolloutTask("b", before, "running", "starting")}, time.Time{}, 1, 2, 0, ""},
+		{"global uses desired tasks", global, []ServiceIdInspectJson{newRolloutTask("a", after, "running", "running"), newRolloutTask("b", after, "running", "preparing"), newRolloutTask("c", before, "shutdown", "shutdown")}, since, 1, 2, 0, ""},
+	}
+	for _, test := range tests {
+		updated, total, failed := rolloutProgress(test.spec, test.tasks, test.since)
+		if updated != test.updated || total != test.total || len(failed) != test.failed {
+			t.Errorf("%s: rolloutProgress() = %d, %d, %d failed, want %d, %d, %d", test.name, updated, total, len(failed), test.updated, test.total, test.failed)
+			continue
+		}
+		if test.failed > 0 && (failed[0].Error != test.failedMessage || failed[0].ExitCode != 1) {
+			t.Errorf("%s: failed = %#v", test.name, failed[0])
+		}
+	}
+}
+
+func TestRolloutFinished(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newService := func(state string, startedAt time.Time, updatedAt time.Time, previousImage string) ServiceInspectJson {
+		svc := ServiceInspectJson{UpdatedAt: updatedAt}
+		svc.Spec = ServiceSpec{Name: "fops", TaskTemplate: TaskSpec{ContainerSpec: ContainerSpec{Image: "farseer/fops:v2"}}, Mode: ServiceMode{Replicated: ReplicatedService{Replicas: 2}}}
+		if previousImage != "" {
+			svc.PreviousSpec = svc.Spec
+			svc.PreviousSpec.TaskTemplate = TaskSpec{ContainerSpec: ContainerSpec{Image: previousImage}}
+		}
+		svc.UpdateStatus.State, svc.UpdateStatus.StartedAt, svc.UpdateStatus.Message = state, startedAt, "update paused due to failure"
+		return svc
+	}
+	before, after := since.Add(-time.Minute), since.Add(time.Minute)
+
+	tests := []struct {
+		name     string
+		svc      ServiceInspectJson
+		since    time.Time
+		updated  int
+		finished bool
+		success  bool
+	}{
+		{"previous update is ignored", newService(RolloutCompleted, before, after, "farseer/fops:v1"), since, 0, false, false},
+		{"waiting for update to start", newService("", time.Time{}, after, "farseer/fops:v1"), since, 0, false, false},
+		{"updating", newService(RolloutUpdating, after, after, "farseer/fops:v1"), since, 1, false, false},
+		{"completed", newService(RolloutCompleted, after, after, "farseer/fops:v1"), since, 2, true, true},
+		{"paused", newService(RolloutPaused, after, after, "farseer/fops:v1"), since, 1, true, false},
+		{"rollback completed", newService(RolloutRollbackCompleted, after, after, "farseer/fops:v1"), since, 2, true, false},
+		{"new service running", newService("", time.Time{}, after, ""), since, 2, true, true},
+		{"new service starting", newService("", time.Time{}, after, ""), time.Time{}, 1, false, false},
+		{"task template unchanged", newService(RolloutCompleted, before, after, "farseer/fops:v2"), since, 2, true, true},
+		{"task template unchanged, tasks starting", newService(RolloutCompleted, before, after, "farseer/fops:v2"), since, 1, false, false},
+		{"not submitted yet", newService(RolloutCompleted, before, since, "farseer/fops:v2"), since, 2, false, false},
+		{"zero since uses current status", newService(RolloutCompleted, before, before, "farseer/fops:v1"), time.Time{}, 2, true, true},
+	}
+	for _, test := range tests {
+		result := RolloutResult{RolloutEvent: RolloutEvent{TasksUpdated: test.updated, TasksTotal: 2}}
+		if rolloutStarted(test.svc, test.since) {
+			result.State, result.Message = test.svc.UpdateStatus.State, test.svc.UpdateStatus.Message
+		}
+		finished := rolloutFinished(test.svc, test.since, &result)
+		if finished != test.finished || result.Success != test.success {
+			t.Errorf("%s: rolloutFinished() = %v, success %v, want %v, success %v", test.name, finished, result.Success, test.finished, test.success)
+		}
+		if finished && !result.Success && result.Reason == "" {
+			t.Errorf("%s: missing reason", test.name)
+		}
+	}
+}
+
+func TestRolloutImageUnchanged(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/distribution/farseer/fops:v1/json":
+			json.NewEncoder(w).Encode(map[string]any{"Descriptor": map[string]any{"digest": digest}})
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{
+				"ID":      "svc1",
+				"Version": map[string]int{"Index": 3},
+				"Spec": map[string]any{
+					"Name":         "fops",
+					"Mode":         map[string]any{"Replicated": map[string]int{"Replicas": 1}},
+					"TaskTemplate": map[string]any{"ContainerSpec": map[string]string{"Image": "farseer/fops:v1@" + digest}},
+					"UpdateConfig": map[string]any{"Parallelism": 1, "FailureAction": "pause", "Order": "start-first"},
+				},
+			})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	// 镜像的 digest 与当前相同：不提交更新，立即返回
+	result, err := service{api: api}.RolloutImage(context.Background(), "fops", "farseer/fops:v1", ImageRolloutOptions{}, nil)
+	if err != nil || !result.Success || result.State != RolloutCompleted {
+		t.Fatalf("RolloutImage() = %#v, %v", result, err)
+	}
+}
@@ -0,0 +1,521 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceSpec 服务的完整配置（POST /services/create、/services/{id}/update 的请求体）
+// UpdateConfig、RollbackConfig、EndpointSpec、TaskTemplate.RestartPolicy 为指针：没有设置时不提交，使用 daemon 的默认值
+// 读取服务详情时这些字段可能为 nil，使用 GetUpdateConfig 等方法读取可以避免判断 nil
+type ServiceSpec struct {
+	Name           string            `json:"Name,omitempty"`           // 服务名称
+	Labels         map[string]string `json:"Labels,omitempty"`         // 服务标签
+	TaskTemplate   TaskSpec          `json:"TaskTemplate"`             // 任务（容器）配置
+	Mode           ServiceMode       `json:"Mode"`                     // 副本模式 replicated、global、replicated-job、global-job
+	UpdateConfig   *UpdateConfig     `json:"UpdateConfig,omitempty"`   // 滚动更新配置
+	RollbackConfig *UpdateConfig     `json:"RollbackConfig,omitempty"` // 回滚配置
+	EndpointSpec   *EndpointSpec     `json:"EndpointSpec,omitempty"`   // 端口发布
+}
+
+// GetUpdateConfig 滚动更新配置，没有设置时返回零值
+func (receiver ServiceSpec) GetUpdateConfig() UpdateConfig {
+	if receiver.UpdateConfig == nil {
+		return UpdateConfig{}
+	}
+	return *receiver.UpdateConfig
+}
+
+// GetRollbackConfig 回滚配置，没有设置时返回零值
+func (receiver ServiceSpec) GetRollbackConfig() UpdateConfig {
+	if receiver.RollbackConfig == nil {
+		return UpdateConfig{}
+	}
+	return *receiver.RollbackConfig
+}
+
+// GetEndpointSpec 端口发布配置，没有设置时返回零值
+func (receiver ServiceSpec) GetEndpointSpec() EndpointSpec {
+	if receiver.EndpointSpec == nil {
+		return EndpointSpec{}
+	}
+	return *receiver.EndpointSpec
+}
+
+// TaskSpec 任务配置
+type TaskSpec struct {
+	ContainerSpec ContainerSpec             `json:"ContainerSpec"`
+	Resources     ResourceRequirements      `json:"Resources"`
+	RestartPolicy *RestartPolicy            `json:"RestartPolicy,omitempty"`
+	Placement     Placement                 `json:"Placement"`
+	Networks      []NetworkAttachmentConfig `json:"Networks,omitempty"`
+	LogDriver     *Driver                   `json:"LogDriver,omitempty"`
+	ForceUpdate   int                       `json:"ForceUpdate"` // 每次+1 会强制重启所有任务
+	Runtime       string                    `json:"Runtime,omitempty"`
+}
+
+// GetRestartPolicy 重启策略，没有设置时返回零值
+func (receiver TaskSpec) GetRestartPolicy() RestartPolicy {
+	if receiver.RestartPolicy == nil {
+		return RestartPolicy{}
+	}
+	return *receiver.RestartPolicy
+}
+
+// ContainerSpec 容器配置
+type ContainerSpec struct {
+	Image           string              `json:"Image"`
+	Labels          map[string]string   `json:"Labels,omitempty"` // 容器标签
+	Command         []string            `json:"Command,omitempty"`
+	Args            []string            `json:"Args,omitempty"`
+	Hostname        string              `json:"Hostname,omitempty"`
+	Env             []string            `json:"Env,omitempty"` // KEY=VALUE
+	Dir             string              `json:"Dir,omitempty"`
+	User            string              `json:"User,omitempty"`
+	Groups          []string            `json:"Groups,omitempty"`
+	Privileges      json.RawMessage     `json:"Privileges,omitempty"`
+	Init            *bool               `json:"Init,omitempty"` // 为空时使用 daemon 的默认配置
+	TTY             bool                `json:"TTY,omitempty"`
+	OpenStdin       bool                `json:"OpenStdin,omitempty"`
+	ReadOnly        bool                `json:"ReadOnly,omitempty"`
+	Mounts          []Mount             `json:"Mounts,omitempty"`
+	StopSignal      string              `json:"StopSignal,omitempty"`
+	StopGracePeriod int64               `json:"StopGracePeriod,omitempty"` // 纳秒
+	Healthcheck     *HealthConfig       `json:"Healthcheck,omitempty"`
+	Hosts           []string            `json:"Hosts,omitempty"` // IP hostname
+	DNSConfig       DNSConfig           `json:"DNSConfig"`
+	Secrets         []ServiceSecretJson `json:"Secrets,omitempty"`
+	Configs         []ServiceConfigJson `json:"Configs,omitempty"`
+	Isolation       string              `json:"Isolation,omitempty"`
+	Sysctls         map[string]string   `json:"Sysctls,omitempty"`
+	CapabilityAdd   []string            `json:"CapabilityAdd,omitempty"`
+	CapabilityDrop  []string            `json:"CapabilityDrop,omitempty"`
+	Ulimits         []Ulimit            `json:"Ulimits,omitempty"`
+	OomScoreAdj     int64               `json:"OomScoreAdj,omitempty"`
+}
+
+// Mount 挂载
+type Mount struct {
+	Type          string         `json:"Type"` // bind volume tmpfs
+	Source        string         `json:"Source,omitempty"`
+	Target        string         `json:"Target"`
+	ReadOnly      bool           `json:"ReadOnly,omitempty"`
+	Consistency   string         `json:"Consistency,omitempty"`
+	BindOptions   *BindOptions   `json:"BindOptions,omitempty"`
+	VolumeOptions *VolumeOptions `json:"VolumeOptions,omitempty"`
+	TmpfsOptions  *TmpfsOptions  `json:"TmpfsOptions,omitempty"`
+}
+
+type BindOptions struct {
+	Propagation  string `json:"Propagation,omitempty"`
+	NonRecursive bool   `json:"NonRecursive,omitempty"`
+}
+
+type VolumeOptions struct {
+	NoCopy       bool              `json:"NoCopy,omitempty"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	DriverConfig *Driver           `json:"DriverConfig,omitempty"`
+}
+
+type TmpfsOptions struct {
+	SizeBytes int64 `json:"SizeBytes,omitempty"`
+	Mode      int   `json:"Mode,omitempty"`
+}
+
+// Driver 驱动（日志、卷）
+type Driver struct {
+	Name    string            `json:"Name"`
+	Options map[string]string `json:"Options,omitempty"`
+}
+
+// HealthConfig 健康检查，时间单位为纳秒
+type HealthConfig struct {
+	Test          []string `json:"Test,omitempty"` // ["CMD-SHELL", "curl -f http://localhost/ || exit 1"]、["NONE"]
+	Interval      int64    `json:"Interval,omitempty"`
+	Timeout       int64    `json:"Timeout,omitempty"`
+	StartPeriod   int64    `json:"StartPeriod,omitempty"`
+	StartInterval int64    `json:"StartInterval,omitempty"`
+	Retries       int      `json:"Retries,omitempty"`
+}
+
+type DNSConfig struct {
+	Nameservers []string `json:"Nameservers,omitempty"`
+	Search      []string `json:"Search,omitempty"`
+	Options     []string `json:"Options,omitempty"`
+}
+
+type Ulimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+type ServiceSecretJson struct {
+	SecretID   string                `json:"SecretID"`
+	SecretName string                `json:"SecretName"`
+	File       ServiceConfigFileJson `json:"File"`
+}
+
+// ResourceRequirements 资源限制和预留
+type ResourceRequirements struct {
+	Limits       Resources `json:"Limits"`
+	Reservations Resources `json:"Reservations"`
+}
+
+type Resources struct {
+	NanoCPUs         int64           `json:"NanoCPUs,omitempty"`    // CPU，1核=1e9
+	MemoryBytes      int64           `json:"MemoryBytes,omitempty"` // 内存（字节）
+	Pids             int64           `json:"Pids,omitempty"`        // 最大进程数（仅 Limits）
+	GenericResources json.RawMessage `json:"GenericResources,omitempty"`
+}
+
+// RestartPolicy 重启策略，时间单位为纳秒
+type RestartPolicy struct {
+	Condition   string `json:"Condition,omitempty"` // none on-failure any
+	Delay       int64  `json:"Delay,omitempty"`
+	MaxAttempts int    `json:"MaxAttempts,omitempty"`
+	Window      int64  `json:"Window,omitempty"`
+}
+
+// Placement 调度约束
+type Placement struct {
+	Constraints []string              `json:"Constraints,omitempty"` // node.role==manager
+	Preferences []PlacementPreference `json:"Preferences,omitempty"`
+	MaxReplicas int                   `json:"MaxReplicas,omitempty"` // 每个节点最多运行的副本数
+	Platforms   []Platform            `json:"Platforms,omitempty"`
+}
+
+type PlacementPreference struct {
+	Spread struct {
+		SpreadDescriptor string `json:"SpreadDescriptor"` // node.labels.zone
+	} `json:"Spread"`
+}
+
+type Platform struct {
+	Architecture string `json:"Architecture"`
+	OS           string `json:"OS"`
+}
+
+type NetworkAttachmentConfig struct {
+	Target     string            `json:"Target"`
+	Aliases    []string          `json:"Aliases,omitempty"`
+	DriverOpts map[string]string `json:"DriverOpts,omitempty"`
+}
+
+// UpdateConfig 滚动更新、回滚配置，时间单位为纳秒
+type UpdateConfig struct {
+	Parallelism     int     `json:"Parallelism"` // 0 表示同时更新所有任务
+	Delay           int64   `json:"Delay,omitempty"`
+	FailureAction   string  `json:"FailureAction,omitempty"` // pause continue rollback
+	Monitor         int64   `json:"Monitor,omitempty"`
+	MaxFailureRatio float64 `json:"MaxFailureRatio,omitempty"`
+	Order           string  `json:"Order,omitempty"` // stop-first start-first
+}
+
+// EndpointSpec 端口发布
+type EndpointSpec struct {
+	Mode  string       `json:"Mode,omitempty"` // vip dnsrr
+	Ports []PortConfig `json:"Ports,omitempty"`
+}
+
+type PortConfig struct {
+	Name          string `json:"Name,omitempty"`
+	Protocol      string `json:"Protocol,omitempty"` // tcp udp sctp
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort,omitempty"`
+	PublishMode   string `json:"PublishMode,omitempty"` // ingress host
+}
+
+// ServiceMode 副本模式，只能设置其中一种
+type ServiceMode struct {
+	Replicated    ReplicatedService `json:"Replicated"`
+	Global        *struct{}         `json:"Global,omitempty"`
+	ReplicatedJob *ReplicatedJob    `json:"ReplicatedJob,omitempty"`
+	GlobalJob     *struct{}         `json:"GlobalJob,omitempty"`
+}
+
+type ReplicatedService struct {
+	Replicas int `json:"Replicas"` // 副本数量
+}
+
+type ReplicatedJob struct {
+	MaxConcurrent    int `json:"MaxConcurrent,omitempty"`    // 同时运行的任务数
+	TotalCompletions int `json:"TotalCompletions,omitempty"` // 需要成功完成的任务数
+}
+
+// IsGlobal 是否为全局服务（每个节点一个任务）
+func (receiver ServiceMode) IsGlobal() bool {
+	return receiver.Global != nil
+}
+
+// IsJob 是否为一次性任务
+func (receiver ServiceMode) IsJob() bool {
+	return receiver.ReplicatedJob != nil || receiver.GlobalJob != nil
+}
+
+// MarshalJSON 只输出当前使用的模式，否则 daemon 会报 multiple modes
+func (receiver ServiceMode) MarshalJSON() ([]byte, error) {
+	switch {
+	case receiver.Global != nil:
+		return []byte(`{"Global":{}}`), nil
+	case receiver.GlobalJob != nil:
+		return []byte(`{"GlobalJob":{}}`), nil
+	case receiver.ReplicatedJob != nil:
+		return json.Marshal(map[string]any{"ReplicatedJob": receiver.ReplicatedJob})
+	default:
+		return json.Marshal(map[string]any{"Replicated": receiver.Replicated})
+	}
+}
+
+// ServiceSpecBuilder 以链式调用构建 ServiceSpec
+type ServiceSpecBuilder struct {
+	spec ServiceSpec
+	err  error
+}
+
+// NewServiceSpec 创建服务配置，默认 1 个副本、start-first 滚动更新
+func NewServiceSpec(serviceName string, dockerImage string) *ServiceSpecBuilder {
+	return &ServiceSpecBuilder{spec: ServiceSpec{
+		Name:         serviceName,
+		TaskTemplate: TaskSpec{ContainerSpec: ContainerSpec{Image: dockerImage}},
+		Mode:         ServiceMode{Replicated: ReplicatedService{Replicas: 1}},
+		UpdateConfig: &UpdateConfig{Parallelism: 1, Order: "start-first", FailureAction: "pause"},
+	}}
+}
+
+// Build 返回构建好的配置
+func (receiver *ServiceSpecBuilder) Build() (ServiceSpec, error) {
+	if receiver.err == nil && receiver.spec.Name == "" {
+		receiver.err = fmt.Errorf("service name is empty")
+	}
+	if receiver.err == nil && receiver.spec.TaskTemplate.ContainerSpec.Image == "" {
+		receiver.err = fmt.Errorf("service %s image is empty", receiver.spec.Name)
+	}
+	return receiver.spec, receiver.err
+}
+
+// Label 服务标签
+func (receiver *ServiceSpecBuilder) Label(key, value string) *ServiceSpecBuilder {
+	if receiver.spec.Labels == nil {
+		receiver.spec.Labels = map[string]string{}
+	}
+	receiver.spec.Labels[key] = value
+	return receiver
+}
+
+// ContainerLabel 容器标签
+func (receiver *ServiceSpecBuilder) ContainerLabel(key, value string) *ServiceSpecBuilder {
+	containerSpec := &receiver.spec.TaskTemplate.ContainerSpec
+	if containerSpec.Labels == nil {
+		containerSpec.Labels = map[string]string{}
+	}
+	containerSpec.Labels[key] = value
+	return receiver
+}
+
+// Env 环境变量
+func (receiver *ServiceSpecBuilder) Env(key, value string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.ContainerSpec.Env = append(receiver.spec.TaskTemplate.ContainerSpec.Env, key+"="+value)
+	return receiver
+}
+
+// Command 覆盖镜像的 ENTRYPOINT
+func (receiver *ServiceSpecBuilder) Command(command ...string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.ContainerSpec.Command = command
+	return receiver
+}
+
+// Args 覆盖镜像的 CMD
+func (receiver *ServiceSpecBuilder) Args(args ...string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.ContainerSpec.Args = args
+	return receiver
+}
+
+// Mount 挂载
+func (receiver *ServiceSpecBuilder) Mount(mount Mount) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.ContainerSpec.Mounts = append(receiver.spec.TaskTemplate.ContainerSpec.Mounts, mount)
+	return receiver
+}
+
+// BindMount 挂载宿主机目录
+func (receiver *ServiceSpecBuilder) BindMount(source, target string, readOnly bool) *ServiceSpecBuilder {
+	return receiver.Mount(Mount{Type: "bind", Source: source, Target: target, ReadOnly: readOnly})
+}
+
+// VolumeMount 挂载卷
+func (receiver *ServiceSpecBuilder) VolumeMount(volumeName, target string, readOnly bool) *ServiceSpecBuilder {
+	return receiver.Mount(Mount{Type: "volume", Source: volumeName, Target: target, ReadOnly: readOnly})
+}
+
+// Network 加入网络，可指定网络内的别名
+func (receiver *ServiceSpecBuilder) Network(networkName string, aliases ...string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Networks = append(receiver.spec.TaskTemplate.Networks, NetworkAttachmentConfig{Target: networkName, Aliases: aliases})
+	return receiver
+}
+
+// PublishPort 发布端口，protocol 默认 tcp，publishMode 默认 ingress
+func (receiver *ServiceSpecBuilder) PublishPort(publishedPort, targetPort int, protocol string, publishMode string) *ServiceSpecBuilder {
+	if receiver.spec.EndpointSpec == nil {
+		receiver.spec.EndpointSpec = &EndpointSpec{}
+	}
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if publishMode == "" {
+		publishMode = "ingress"
+	}
+	receiver.spec.EndpointSpec.Ports = append(receiver.spec.EndpointSpec.Ports, PortConfig{Protocol: protocol, TargetPort: targetPort, PublishedPort: publishedPort, PublishMode: publishMode})
+	return receiver
+}
+
+// Config 挂载配置文件 docker config
+func (receiver *ServiceSpecBuilder) Config(config ServiceConfigJson) *ServiceSpecBuilder {
+	if config.File.Name != "" && config.File.UID == "" {
+		config.File.UID, config.File.GID, config.File.Mode = "0", "0", 0444
+	}
+	receiver.spec.TaskTemplate.ContainerSpec.Configs = append(receiver.spec.TaskTemplate.ContainerSpec.Configs, config)
+	return receiver
+}
+
+// Secret 挂载密钥 docker secret，target 为空时挂载到 /run/secrets/{secretName}
+func (receiver *ServiceSpecBuilder) Secret(secret ServiceSecretJson) *ServiceSpecBuilder {
+	if secret.File.Name == "" {
+		secret.File.Name = secret.SecretName
+	}
+	if secret.File.UID == "" {
+		secret.File.UID, secret.File.GID, secret.File.Mode = "0", "0", 0444
+	}
+	receiver.spec.TaskTemplate.ContainerSpec.Secrets = append(receiver.spec.TaskTemplate.ContainerSpec.Secrets, secret)
+	return receiver
+}
+
+// LimitCPU 限制CPU核数 0.5
+func (receiver *ServiceSpecBuilder) LimitCPU(cpus float64) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Resources.Limits.NanoCPUs = int64(cpus * 1e9)
+	return receiver
+}
+
+// LimitMemory 限制内存 512m、1g
+func (receiver *ServiceSpecBuilder) LimitMemory(memory string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Resources.Limits.MemoryBytes = receiver.parseMemory(memory)
+	return receiver
+}
+
+// ReserveCPU 预留CPU核数
+func (receiver *ServiceSpecBuilder) ReserveCPU(cpus float64) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Resources.Reservations.NanoCPUs = int64(cpus * 1e9)
+	return receiver
+}
+
+// ReserveMemory 预留内存 256m
+func (receiver *ServiceSpecBuilder) ReserveMemory(memory string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Resources.Reservations.MemoryBytes = receiver.parseMemory(memory)
+	return receiver
+}
+
+func (receiver *ServiceSpecBuilder) parseMemory(memory string) int64 {
+	bytes, err := ParseMemoryBytes(memory)
+	if err != nil && receiver.err == nil {
+		receiver.err = err
+	}
+	return bytes
+}
+
+// Constraint 调度约束 node.role==manager、node.labels.zone==a
+func (receiver *ServiceSpecBuilder) Constraint(expression string) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Placement.Constraints = append(receiver.spec.TaskTemplate.Placement.Constraints, expression)
+	return receiver
+}
+
+// Spread 按节点标签均匀分布 node.labels.zone
+func (receiver *ServiceSpecBuilder) Spread(descriptor string) *ServiceSpecBuilder {
+	var preference PlacementPreference
+	preference.Spread.SpreadDescriptor = descriptor
+	receiver.spec.TaskTemplate.Placement.Preferences = append(receiver.spec.TaskTemplate.Placement.Preferences, preference)
+	return receiver
+}
+
+// MaxReplicasPerNode 每个节点最多运行的副本数
+func (receiver *ServiceSpecBuilder) MaxReplicasPerNode(maxReplicas int) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.Placement.MaxReplicas = maxReplicas
+	return receiver
+}
+
+// RestartPolicy 重启策略 condition: none on-failure any
+func (receiver *ServiceSpecBuilder) RestartPolicy(condition string, delay time.Duration, maxAttempts int, window time.Duration) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.RestartPolicy = &RestartPolicy{Condition: condition, Delay: int64(delay), MaxAttempts: maxAttempts, Window: int64(window)}
+	return receiver
+}
+
+// UpdateConfig 滚动更新配置
+func (receiver *ServiceSpecBuilder) UpdateConfig(config UpdateConfig) *ServiceSpecBuilder {
+	receiver.spec.UpdateConfig = &config
+	return receiver
+}
+
+// RollbackConfig 回滚配置
+func (receiver *ServiceSpecBuilder) RollbackConfig(config UpdateConfig) *ServiceSpecBuilder {
+	receiver.spec.RollbackConfig = &config
+	return receiver
+}
+
+// Healthcheck 健康检查 test: ["CMD-SHELL", "curl -f http://localhost/ || exit 1"]
+func (receiver *ServiceSpecBuilder) Healthcheck(test []string, interval, timeout, startPeriod time.Duration, retries int) *ServiceSpecBuilder {
+	receiver.spec.TaskTemplate.ContainerSpec.Healthcheck = &HealthConfig{Test: test, Interval: int64(interval), Timeout: int64(timeout), StartPeriod: int64(startPeriod), Retries: retries}
+	return receiver
+}
+
+// Replicas 副本模式，指定副本数量
+func (receiver *ServiceSpecBuilder) Replicas(replicas int) *ServiceSpecBuilder {
+	receiver.spec.Mode = ServiceMode{Replicated: ReplicatedService{Replicas: replicas}}
+	return receiver
+}
+
+// Global 全局模式，每个节点运行一个任务
+func (receiver *ServiceSpecBuilder) Global() *ServiceSpecBuilder {
+	receiver.spec.Mode = ServiceMode{Global: &struct{}{}}
+	return receiver
+}
+
+// Spec 直接修改配置（用于构建器没有覆盖的字段）
+func (receiver *ServiceSpecBuilder) Spec(fn func(spec *ServiceSpec)) *ServiceSpecBuilder {
+	fn(&receiver.spec)
+	return receiver
+}
+
+// ParseMemoryBytes 解析内存大小 512m、1g、1024k、1073741824
+func ParseMemoryBytes(memory string) (int64, error) {
+	memory = strings.ToLower(strings.TrimSpace(memory))
+	if memory == "" {
+		return 0, nil
+	}
+
+	value := strings.TrimSuffix(strings.TrimSuffix(memory, "b"), "i")
+	if value == "" {
+		return 0, fmt.Errorf("invalid memory size %q", memory)
+	}
+
+	unit := int64(1)
+	switch value[len(value)-1] {
+	case 'k':
+		unit = 1024
+	case 'm':
+		unit = 1024 * 1024
+	case 'g':
+		unit = 1024 * 1024 * 1024
+	case 't':
+		unit = 1024 * 1024 * 1024 * 1024
+	}
+	if unit > 1 {
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid memory size %q", memory)
+	}
+	return int64(number * float64(unit)), nil
+}
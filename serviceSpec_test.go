@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceSpecBuilder(t *testing.T) {
+	spec, err := NewServiceSpec("fops", "farseer/fops:v1").
+		Env("Database", "mysql").
+		BindMount("/etc/localtime", "/etc/localtime", true).
+		Network("net", "fops-api").
+		PublishPort(8888, 80, "", "").
+		Config(ServiceConfigJson{ConfigName: "fops_config_v1", File: ServiceConfigFileJson{Name: "/app/farseer.yaml"}}).
+		LimitCPU(0.5).
+		LimitMemory("512m").
+		Constraint("node.role==manager").
+		Healthcheck([]string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"}, 10*time.Second, 3*time.Second, 0, 3).
+		Global().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.TaskTemplate.Resources.Limits.NanoCPUs != 500000000 || spec.TaskTemplate.Resources.Limits.MemoryBytes != 512*1024*1024 {
+		t.Fatalf("Resources = %#v", spec.TaskTemplate.Resources)
+	}
+	if spec.TaskTemplate.ContainerSpec.Configs[0].File.Mode != 0444 || spec.EndpointSpec.Ports[0].PublishMode != "ingress" {
+		t.Fatalf("Build() = %#v", spec)
+	}
+
+	body, _ := json.Marshal(spec)
+	if !strings.Contains(string(body), `"Mode":{"Global":{}}`) {
+		t.Fatalf("json.Marshal() = %s", body)
+	}
+
+	if _, err = NewServiceSpec("fops", "farseer/fops:v1").LimitMemory("lots").Build(); err == nil {
+		t.Fatal("Build() expected invalid memory error")
+	}
+}
+
+func TestParseMemoryBytes(t *testing.T) {
+	tests := map[string]int64{"": 0, "1024": 1024, "512m": 512 * 1024 * 1024, "1.5G": 1536 * 1024 * 1024, "2gb": 2 * 1024 * 1024 * 1024, "64k": 64 * 1024}
+	for memory, want := range tests {
+		if got, err := ParseMemoryBytes(memory); err != nil || got != want {
+			t.Fatalf("ParseMemoryBytes(%q) = %d, %v, want %d", memory, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	serviceUpdateMaxRetries = 5                      // 版本冲突时的最大重试次数
+	serviceUpdateRetryDelay = 200 * time.Millisecond // 重试间隔（逐次递增）
+)
+
+// ServiceUpdateResult 更新服务的结果
+type ServiceUpdateResult struct {
+	ServiceID string   `json:"-"`        // 服务ID
+	Version   int      `json:"-"`        // 提交时使用的版本号
+	Retries   int      `json:"-"`        // 因版本冲突重试的次数
+	Warnings  []string `json:"Warnings"` // 警告信息
+}
+
+// IsUpdateOutOfSequence 是否为版本冲突（读取配置后服务被其它请求修改过）
+func IsUpdateOutOfSequence(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "update out of sequence")
+}
+
+// Update 读取服务当前配置，通过 mutate 修改后提交，版本冲突时自动重新读取并重试
+func (receiver service) Update(serviceName string, mutate func(spec *ServiceSpec) error) (ServiceUpdateResult, error) {
+	return receiver.UpdateContext(context.Background(), serviceName, mutate)
+}
+
+// UpdateContext 同 Update（支持 ctx 控制超时/取消）
+func (receiver service) UpdateContext(ctx context.Context, serviceName string, mutate func(spec *ServiceSpec) error) (ServiceUpdateResult, error) {
+	return receiver.update(ctx, serviceName, nil, func(current ServiceInspectJson) (ServiceSpec, error) {
+		spec := current.Spec
+		if err := mutate(&spec); err != nil {
+			return spec, err
+		}
+		return spec, nil
+	})
+}
+
+// update 统一的更新流程：读取配置和版本号 -> 生成新配置 -> POST /services/{id}/update -> 版本冲突时重试
+func (receiver service) update(ctx context.Context, serviceName string, query url.Values, build func(current ServiceInspectJson) (ServiceSpec, error)) (ServiceUpdateResult, error) {
+	var result ServiceUpdateResult
+	for attempt := 0; ; attempt++ {
+		current, rawSpec, err := receiver.inspectSpec(ctx, serviceName)
+		if err != nil {
+			return result, err
+		}
+
+		spec, err := build(current)
+		if err != nil {
+			return result, err
+		}
+		// ServiceSpec 没有覆盖的字段（PluginSpec、新版本 daemon 增加的字段等）按读取到的原样提交
+		body, err := mergeUnknownFields(rawSpec, spec)
+		if err != nil {
+			return result, err
+		}
+
+		headers, err := receiver.registryAuthHeaders(spec.TaskTemplate.ContainerSpec.Image)
+		if err != nil {
+			return result, err
+		}
+
+		values := url.Values{}
+		for k, v := range query {
+			values[k] = v
+		}
+		values.Set("version", fmt.Sprintf("%d", current.Version.Index))
+		if headers == nil {
+			// 没有认证信息时，沿用服务创建时保存的认证信息
+			values.Set("registryAuthFrom", "spec")
+		}
+
+		// curl --unix-socket /var/run/docker.sock -X POST -d '{Spec}' http://localhost/services/fops/update?version=123
+		updateUrl := receiver.api.URL(fmt.Sprintf("/services/%s/update?%s", current.ID, values.Encode()))
+		result, err = UnixRequestDecode[ServiceUpdateResult](ctx, receiver.api.httpClient, http.MethodPost, updateUrl, body, headers)
+		result.ServiceID, result.Version, result.Retries = current.ID, current.Version.Index, attempt
+		if err == nil || !IsUpdateOutOfSequence(err) || attempt >= serviceUpdateMaxRetries {
+			return result, err
+		}
+
+		// 版本冲突：等待后重新读取最新配置再提交
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * serviceUpdateRetryDelay):
+		}
+	}
+}
+
+// inspectSpec 读取服务的配置和版本号（保留镜像的 digest，用于原样提交），同时返回 Spec 的原始 JSON
+func (receiver service) inspectSpec(ctx context.Context, serviceName string) (ServiceInspectJson, json.RawMessage, error) {
+	var current ServiceInspectJson
+	url := receiver.api.URL(fmt.Sprintf("/services/%s", serviceName))
+	raw, err := UnixRequestDecode[json.RawMessage](ctx, receiver.api.httpClient, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return current, nil, err
+	}
+	var rawService struct {
+		Spec json.RawMessage `json:"Spec"`
+	}
+	if err = json.Unmarshal(raw, &current); err != nil {
+		return current, nil, err
+	}
+	if err = json.Unmarshal(raw, &rawService); err != nil {
+		return current, nil, err
+	}
+	if current.ID == "" {
+		return current, nil, errors.New("no such service")
+	}
+	return current, rawService.Spec, nil
+}
+
+// mergeUnknownFields 将 typed 序列化后与 raw 合并：typed 中定义的字段以 typed 为准，typed 的类型中没有定义的字段保留 raw 中的值
+// 数组中的元素（Mounts、Networks 等）按内容匹配：与 raw 中某个元素的已定义字段完全相同时，保留该元素的未定义字段
+// raw 中不存在、typed 中为零值的字段不提交，避免给 PluginSpec 类型的任务补上空的 ContainerSpec
+func mergeUnknownFields(raw json.RawMessage, typed any) (json.RawMessage, error) {
+	typedJson, err := json.Marshal(typed)
+	if err != nil || len(raw) == 0 {
+		return typedJson, err
+	}
+	rawValue, err := decodeJsonValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	typedValue, err := decodeJsonValue(typedJson)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeJsonValue(rawValue, typedValue, reflect.TypeOf(typed)))
+}
+
+func mergeJsonValue(raw any, typed any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		rawObject, isRawObject := raw.(map[string]any)
+		typedObject, isTypedObject := typed.(map[string]any)
+		if !isRawObject || !isTypedObject {
+			return typed
+		}
+		fields := jsonFields(t)
+		for key, rawField := range rawObject {
+			fieldType, known := fields[key]
+			if !known {
+				typedObject[key] = rawField
+			} else if typedField, exists := typedObject[key]; exists {
+				typedObject[key] = mergeJsonValue(rawField, typedField, fieldType)
+			}
+		}
+		for key, fieldType := range fields {
+			if _, exists := rawObject[key]; !exists && isZeroJsonValue(typedObject[key], fieldType) {
+				delete(typedObject, key)
+			}
+		}
+		return typedObject
+	case reflect.Slice:
+		rawArray, isRawArray := raw.([]any)
+		typedArray, isTypedArray := typed.([]any)
+		elemType := t.Elem()
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if !isRawArray || !isTypedArray || elemType.Kind() != reflect.Struct {
+			return typed
+		}
+		used := make([]bool, len(rawArray))
+		for i, typedItem := range typedArray {
+			for j, rawItem := range rawArray {
+				if !used[j] && sameKnownFields(rawItem, typedItem, elemType) {
+					used[j] = true
+					typedArray[i] = mergeJsonValue(rawItem, typedItem, elemType)
+					break
+				}
+			}
+		}
+		return typedArray
+	}
+	return typed
+}
+
+// sameKnownFields raw 按 t 解析后是否与 typed 相同（只比较 t 中定义的字段）
+func sameKnownFields(raw any, typed any, t reflect.Type) bool {
+	rawJson, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	value := reflect.New(t)
+	if json.Unmarshal(rawJson, value.Interface()) != nil {
+		return false
+	}
+	known, err := json.Marshal(value.Interface())
+	if err != nil {
+		return false
+	}
+	knownValue, err := decodeJsonValue(known)
+	return err == nil && reflect.DeepEqual(knownValue, typed)
+}
+
+// isZeroJsonValue value 是否为 t 的零值序列化后的结果
+func isZeroJsonValue(value any, t reflect.Type) bool {
+	if value == nil {
+		return true
+	}
+	zero, err := json.Marshal(reflect.Zero(t).Interface())
+	if err != nil {
+		return false
+	}
+	zeroValue, err := decodeJsonValue(zero)
+	return err == nil && reflect.DeepEqual(zeroValue, value)
+}
+
+// jsonFields 结构体序列化时的字段名及类型（包括匿名嵌入的结构体）
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for key, fieldType := range jsonFields(field.Type) {
+				fields[key] = fieldType
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// decodeJsonValue 解析为 map/slice，数字保留原样（避免 int64 精度丢失）
+func decodeJsonValue(data []byte) (any, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	return value, err
+}
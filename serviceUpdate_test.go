@@ -0,0 +1,172 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestDockerAPI 使用 httptest 模拟 docker daemon
+func newTestDockerAPI(t *testing.T, handler http.HandlerFunc) *dockerAPI {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &dockerAPI{
+		httpClient:  server.Client(),
+		endpoint:    dockerEndpoint{baseURL: server.URL},
+		credentials: NewMemoryCredentialStore(),
+	}
+}
+
+func TestServiceUpdateRetriesOutOfSequence(t *testing.T) {
+	version, posts := 10, 0
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{
+				"ID":      "svc1",
+				"Version": map[string]int{"Index": version},
+				"Spec":    map[string]any{"Name": "fops", "Mode": map[string]any{"Replicated": map[string]int{"Replicas": 1}}, "TaskTemplate": map[string]any{"ContainerSpec": map[string]string{"Image": "farseer/fops:v1"}}},
+			})
+		case http.MethodPost:
+			posts++
+			if r.URL.Query().Get("version") == "10" {
+				// 模拟其它请求先修改了服务
+				version = 11
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"message": "rpc error: code = Unknown desc = update out of sequence"})
+				return
+			}
+
+			var spec ServiceSpec
+			json.NewDecoder(r.Body).Decode(&spec)
+			if spec.TaskTemplate.ContainerSpec.Image != "farseer/fops:v2" || spec.Mode.Replicated.Replicas != 3 || spec.UpdateConfig.Order != "start-first" {
+				t.Errorf("unexpected spec %#v", spec)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"Warnings": []string{"image could not be accessed"}})
+		}
+	})
+
+	result, err := service{api: api}.Update("fops", func(spec *ServiceSpec) error {
+		setServiceImage(spec, "farseer/fops:v2", 0)
+		return setServiceReplicas(spec, 3)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 2 || result.Retries != 1 || result.Version != 11 || len(result.Warnings) != 1 {
+		t.Fatalf("Update() = %#v, posts = %d", result, posts)
+	}
+}
+
+func TestMergeUnknownFields(t *testing.T) {
+	raw := json.RawMessage(`{
+	"Name": "fops",
+	"Networks": [{"Target": "net1"}],
+	"TaskTemplate": {
+		"ContainerSpec": {
+			"Image": "farseer/fops:v1",
+			"Init": false,
+			"Mounts": [
+				{"Type": "bind", "Source": "/data", "Target": "/data", "BindOptions": {"CreateMountpoint": true}},
+				{"Type": "tmpfs", "Target": "/tmp", "TmpfsOptions": {"SizeBytes": 1024, "Options": [["exec"]]}},
+				{"Type": "volume", "Source": "logs", "Target": "/logs", "VolumeOptions": {"Subpath": "fops"}}
+			]
+		},
+		"NetworkAttachmentSpec": {"ContainerID": "abc"},
+		"ForceUpdate": 0
+	},
+	"Mode": {"Replicated": {"Replicas": 1}}
+}`)
+	var current ServiceSpec
+	if err := json.Unmarshal(raw, &current); err != nil {
+		t.Fatal(err)
+	}
+	if current.TaskTemplate.ContainerSpec.Init == nil || *current.TaskTemplate.ContainerSpec.Init {
+		t.Fatalf("Init = %v, want explicit false", current.TaskTemplate.ContainerSpec.Init)
+	}
+
+	// 修改镜像、副本数，删除 /logs 挂载
+	current.TaskTemplate.ContainerSpec.Image = "farseer/fops:v2"
+	current.TaskTemplate.ContainerSpec.Mounts = current.TaskTemplate.ContainerSpec.Mounts[:2]
+	current.Mode.Replicated.Replicas = 3
+	body, err := mergeUnknownFields(raw, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var merged map[string]any
+	json.Unmarshal(body, &merged)
+	taskTemplate := merged["TaskTemplate"].(map[string]any)
+	containerSpec := taskTemplate["ContainerSpec"].(map[string]any)
+	mounts := containerSpec["Mounts"].([]any)
+	tests := map[string]bool{
+		"Spec.Networks":                       merged["Networks"] != nil,
+		"TaskTemplate.NetworkAttachmentSpec":  taskTemplate["NetworkAttachmentSpec"] != nil,
+		"ContainerSpec.Image":                 containerSpec["Image"] == "farseer/fops:v2",
+		"ContainerSpec.Init":                  containerSpec["Init"] == false,
+		"Mode.Replicated.Replicas":            merged["Mode"].(map[string]any)["Replicated"].(map[string]any)["Replicas"] == float64(3),
+		"Mounts removed":                      len(mounts) == 2,
+		"BindOptions.CreateMountpoint":        mounts[0].(map[string]any)["BindOptions"].(map[string]any)["CreateMountpoint"] == true,
+		"TmpfsOptions.Options":                mounts[1].(map[string]any)["TmpfsOptions"].(map[string]any)["Options"] != nil,
+		"DNSConfig not added":                 containerSpec["DNSConfig"] == nil,
+		"TaskTemplate.Placement not added":    taskTemplate["Placement"] == nil,
+		"TaskTemplate.ForceUpdate kept":       taskTemplate["ForceUpdate"] == float64(0),
+		"TaskTemplate.ContainerSpec not lost": containerSpec != nil,
+	}
+	for name, ok := range tests {
+		if !ok {
+			t.Errorf("%s: unexpected merged spec %s", name, body)
+		}
+	}
+
+	// PluginSpec 类型的任务不会补上空的 ContainerSpec
+	plugin := json.RawMessage(`{"Name":"plugin","TaskTemplate":{"PluginSpec":{"Name":"vieux/sshfs"},"Runtime":"plugin"},"Mode":{"Global":{}}}`)
+	var pluginSpec ServiceSpec
+	json.Unmarshal(plugin, &pluginSpec)
+	pluginSpec.Labels = map[string]string{"a": "b"}
+	body, _ = mergeUnknownFields(plugin, pluginSpec)
+	if want := `{"Labels":{"a":"b"},"Mode":{"Global":{}},"Name":"plugin","TaskTemplate":{"PluginSpec":{"Name":"vieux/sshfs"},"Runtime":"plugin"}}`; string(body) != want {
+		t.Fatalf("mergeUnknownFields(plugin) = %s, want %s", body, want)
+	}
+}
+
+func TestSetImagesPinsDigest(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var submitted map[string]any
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/distribution/farseer/fops:v2/json":
+			json.NewEncoder(w).Encode(map[string]any{"Descriptor": map[string]any{"digest": digest}})
+		case r.URL.Path == "/distribution/farseer/private:v1/json":
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"message": "access denied"})
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"ID":"svc1","Version":{"Index":1},"Spec":{"Name":"fops","TaskTemplate":{"ContainerSpec":{"Image":"farseer/fops:v1"},"PluginSpec":null,"Runtime":"container"},"Mode":{"Replicated":{"Replicas":1}},"Networks":[{"Target":"net1"}]}}`))
+		case r.Method == http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&submitted)
+			w.Write([]byte(`{}`))
+		}
+	})
+	client := service{api: api}
+
+	if code := client.SetImages("fops", "farseer/fops:v2", 0).Wait(); code != 0 {
+		t.Fatalf("SetImages() exit code = %d", code)
+	}
+	containerSpec := submitted["TaskTemplate"].(map[string]any)["ContainerSpec"].(map[string]any)
+	if containerSpec["Image"] != "farseer/fops:v2@"+digest || submitted["Networks"] == nil {
+		t.Fatalf("submitted spec = %#v", submitted)
+	}
+
+	// 仓库无法访问时，使用原镜像并给出警告
+	image, warning := client.pinImageDigest(context.Background(), "farseer/private:v1")
+	if image != "farseer/private:v1" || !strings.Contains(warning, "access denied") {
+		t.Fatalf("pinImageDigest() = %q, %q", image, warning)
+	}
+	// 已经固定 digest 的镜像不再查询
+	if image, _ = client.pinImageDigest(context.Background(), "farseer/fops:v1@"+digest); image != "farseer/fops:v1@"+digest {
+		t.Fatalf("pinImageDigest() = %q", image)
+	}
+}
@@ -0,0 +1,415 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/farseer-go/collections"
+)
+
+var stackNamespaceRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+type stack struct {
+	api *dockerAPI
+}
+
+// StackVO stack 列表（docker stack ls）
+type StackVO struct {
+	Name     string // stack 名称
+	Services int    // 服务数量
+}
+
+// StackDeployOptions 部署 stack 的参数
+type StackDeployOptions struct {
+	Prune bool // 删除 compose 文件中已不存在的服务
+}
+
+// StackDeployResult 部署 stack 的结果
+type StackDeployResult struct {
+	Namespace string   // stack 名称
+	Networks  []string // 新创建的网络
+	Configs   []string // 新创建的配置
+	Secrets   []string // 新创建的密钥
+	Created   []string // 新创建的服务
+	Updated   []string // 更新的服务
+	Unchanged []string // 配置没有变化、没有提交更新的服务
+	Removed   []string // prune 删除的服务
+	Warnings  []string // daemon 返回的警告
+}
+
+// stackObject 网络、配置、密钥的列表项
+type stackObject struct {
+	ID   string `json:"ID"`
+	Name string `json:"Name"` // 网络
+	Spec struct {
+		Name   string            `json:"Name"` // 配置、密钥
+		Labels map[string]string `json:"Labels"`
+		Data   string            `json:"Data"`
+	} `json:"Spec"`
+}
+
+// Deploy 部署 stack：创建网络、配置、密钥，创建或更新服务，Prune 时删除多余的服务
+// 所有资源都会打上 com.docker.stack.namespace 标签
+// 配置不可修改：同名配置的内容发生变化时返回错误，需要修改 compose 中的名称；已存在的密钥无法读取内容，直接复用
+func (receiver stack) Deploy(ctx context.Context, namespace string, compose ComposeFile, options StackDeployOptions) (StackDeployResult, error) {
+	result := StackDeployResult{Namespace: namespace}
+	if !stackNamespaceRegexp.MatchString(namespace) {
+		return result, fmt.Errorf("invalid stack name %q", namespace)
+	}
+
+	specs, err := compose.ServiceSpecs(namespace)
+	if err != nil {
+		return result, err
+	}
+
+	// 1. 网络
+	if err = receiver.deployNetworks(ctx, namespace, compose, specs, &result); err != nil {
+		return result, err
+	}
+
+	// 2. 配置、密钥，并将 ID 填充到服务配置
+	configIds, secretIds, err := receiver.deployFiles(ctx, namespace, compose, specs, &result)
+	if err != nil {
+		return result, err
+	}
+	for index := range specs {
+		containerSpec := &specs[index].TaskTemplate.ContainerSpec
+		for i := range containerSpec.Configs {
+			containerSpec.Configs[i].ConfigID = configIds[containerSpec.Configs[i].ConfigName]
+		}
+		for i := range containerSpec.Secrets {
+			containerSpec.Secrets[i].SecretID = secretIds[containerSpec.Secrets[i].SecretName]
+		}
+	}
+
+	// 3. 服务
+	existing, err := receiver.services(ctx, namespace)
+	if err != nil {
+		return result, err
+	}
+	currentServices := map[string]ServiceInspectJson{}
+	for _, svc := range existing {
+		currentServices[svc.Spec.Name] = svc
+	}
+
+	svc := service{api: receiver.api}
+	for _, spec := range specs {
+		if _, exists := currentServices[spec.Name]; !exists {
+			created, err := svc.CreateSpec(spec)
+			if err != nil {
+				return result, fmt.Errorf("create service %s failed: %w", spec.Name, err)
+			}
+			result.Created = append(result.Created, spec.Name)
+			result.Warnings = append(result.Warnings, created.Warnings...)
+			continue
+		}
+
+		delete(currentServices, spec.Name)
+		updated, err := svc.update(ctx, spec.Name, nil, func(current ServiceInspectJson) (ServiceSpec, error) {
+			next := stackUpdateSpec(current.Spec, spec)
+			if reflect.DeepEqual(next, current.Spec) {
+				return next, errServiceSpecUnchanged
+			}
+			return next, nil
+		})
+		if errors.Is(err, errServiceSpecUnchanged) {
+			result.Unchanged = append(result.Unchanged, spec.Name)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("update service %s failed: %w", spec.Name, err)
+		}
+		result.Updated = append(result.Updated, spec.Name)
+		result.Warnings = append(result.Warnings, updated.Warnings...)
+	}
+
+	// 4. 删除 compose 中已不存在的服务
+	if options.Prune {
+		for _, name := range sortedKeys(currentServices) {
+			if err = svc.Delete(currentServices[name].ID); err != nil {
+				return result, fmt.Errorf("remove service %s failed: %w", name, err)
+			}
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	return result, nil
+}
+
+// stackUpdateSpec 在 compose 生成的配置上保留当前服务的 ForceUpdate，镜像未变时保留 digest，避免无变化的服务被重启
+func stackUpdateSpec(current ServiceSpec, next ServiceSpec) ServiceSpec {
+	next.TaskTemplate.ForceUpdate = current.TaskTemplate.ForceUpdate
+
+	currentImage, currentErr := ParseImageReference(current.TaskTemplate.ContainerSpec.Image)
+	nextImage, nextErr := ParseImageReference(next.TaskTemplate.ContainerSpec.Image)
+	if currentErr == nil && nextErr == nil && nextImage.Digest == "" && currentImage.WithoutDigest().Equal(nextImage) {
+		next.TaskTemplate.ContainerSpec.Image = current.TaskTemplate.ContainerSpec.Image
+	}
+	return next
+}
+
+// deployNetworks 创建服务用到的网络，external 网络必须已存在
+func (receiver stack) deployNetworks(ctx context.Context, namespace string, compose ComposeFile, specs []ServiceSpec, result *StackDeployResult) error {
+	existing, err := receiver.objects(ctx, "networks", namespace)
+	if err != nil {
+		return err
+	}
+	existingNames := map[string]bool{}
+	for _, network := range existing {
+		existingNames[network.Name] = true
+	}
+
+	// 服务用到的网络：实际名称 -> compose 中的 key
+	used := map[string]string{}
+	for _, spec := range specs {
+		for _, network := range spec.TaskTemplate.Networks {
+			for key, declared := range compose.Networks {
+				if stackObjectName(namespace, key, declared.Name, declared.External) == network.Target {
+					used[network.Target] = key
+				}
+			}
+			if _, exists := used[network.Target]; !exists {
+				used[network.Target] = "default"
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(used) {
+		declared := compose.Networks[used[name]]
+		if declared.External.External {
+			if _, err := UnixRequestDecode[stackObject](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/networks/"+url.PathEscape(name)), nil, nil); err != nil {
+				return fmt.Errorf("network %s is declared as external, but could not be found: %w", name, err)
+			}
+			continue
+		}
+		if existingNames[name] {
+			continue
+		}
+
+		labels := map[string]string{StackNamespaceLabel: namespace}
+		for key, value := range declared.Labels {
+			labels[key] = value
+		}
+		driver := declared.Driver
+		if driver == "" {
+			driver = "overlay"
+		}
+
+		// curl --unix-socket /var/run/docker.sock -X POST -d '{"Name":"fops_default","Driver":"overlay"}' http://localhost/networks/create
+		body := map[string]any{
+			"Name":           name,
+			"Driver":         driver,
+			"CheckDuplicate": true,
+			"Attachable":     declared.Attachable,
+			"Internal":       declared.Internal,
+			"Labels":         labels,
+			"Options":        declared.DriverOpts,
+		}
+		if _, err = UnixRequestDecode[struct{ Id string }](ctx, receiver.api.httpClient, http.MethodPost, receiver.api.URL("/networks/create"), body, nil); err != nil {
+			return fmt.Errorf("create network %s failed: %w", name, err)
+		}
+		result.Networks = append(result.Networks, name)
+	}
+	return nil
+}
+
+// deployFiles 创建服务用到的配置、密钥，返回 名称 -> ID
+func (receiver stack) deployFiles(ctx context.Context, namespace string, compose ComposeFile, specs []ServiceSpec, result *StackDeployResult) (map[string]string, map[string]string, error) {
+	configIds, secretIds := map[string]string{}, map[string]string{}
+	for _, spec := range specs {
+		for _, item := range spec.TaskTemplate.ContainerSpec.Configs {
+			configIds[item.ConfigName] = ""
+		}
+		for _, item := range spec.TaskTemplate.ContainerSpec.Secrets {
+			secretIds[item.SecretName] = ""
+		}
+	}
+
+	for _, kind := range []struct {
+		resource string
+		declared map[string]ComposeFileObject
+		ids      map[string]string
+		created  *[]string
+	}{
+		{"configs", compose.Configs, configIds, &result.Configs},
+		{"secrets", compose.Secrets, secretIds, &result.Secrets},
+	} {
+		for _, key := range sortedKeys(kind.declared) {
+			declared := kind.declared[key]
+			name := stackObjectName(namespace, key, declared.Name, declared.External)
+			if _, used := kind.ids[name]; !used {
+				continue
+			}
+
+			current, err := UnixRequestDecode[stackObject](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/%s/%s", kind.resource, url.PathEscape(name))), nil, nil)
+			var apiErr *APIError
+			if err != nil && !(errors.As(err, &apiErr) && apiErr.IsNotFound()) {
+				return nil, nil, err
+			}
+			if declared.External.External {
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s %s is declared as external, but could not be found", kind.resource, name)
+				}
+				kind.ids[name] = current.ID
+				continue
+			}
+
+			data, readErr := compose.fileObjectContent(declared)
+			if readErr != nil {
+				return nil, nil, fmt.Errorf("%s %s: %w", kind.resource, key, readErr)
+			}
+			if err == nil {
+				// 已存在：配置可以比较内容，密钥无法读取内容
+				if kind.resource == "configs" && current.Spec.Data != base64.StdEncoding.EncodeToString(data) {
+					return nil, nil, fmt.Errorf("config %s already exists with different content, configs are immutable: rename it in the compose file", name)
+				}
+				kind.ids[name] = current.ID
+				continue
+			}
+
+			labels := map[string]string{StackNamespaceLabel: namespace}
+			for k, v := range declared.Labels {
+				labels[k] = v
+			}
+			// curl --unix-socket /var/run/docker.sock -X POST -d '{"Name":"fops_app","Data":"base64"}' http://localhost/secrets/create
+			body := ConfigCreateRequest{Name: name, Labels: labels, Data: base64.StdEncoding.EncodeToString(data)}
+			if declared.TemplateDriver != "" {
+				body.Templating = &ConfigTemplating{Name: declared.TemplateDriver}
+			}
+			created, err := UnixRequestDecode[struct{ ID string }](ctx, receiver.api.httpClient, http.MethodPost, receiver.api.URL(fmt.Sprintf("/%s/create", kind.resource)), body, nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("create %s %s failed: %w", kind.resource, name, err)
+			}
+			kind.ids[name] = created.ID
+			*kind.created = append(*kind.created, name)
+		}
+	}
+	return configIds, secretIds, nil
+}
+
+// fileObjectContent 读取配置、密钥的内容：file、content、environment
+func (receiver ComposeFile) fileObjectContent(declared ComposeFileObject) ([]byte, error) {
+	switch {
+	case declared.File != "":
+		return os.ReadFile(receiver.path(declared.File))
+	case declared.Environment != "":
+		value, exists := receiver.lookupEnv(declared.Environment) // 与变量插值使用相同的环境变量
+		if !exists {
+			return nil, fmt.Errorf("environment variable %s is not set", declared.Environment)
+		}
+		return []byte(value), nil
+	case declared.Content != "":
+		return []byte(declared.Content), nil
+	default:
+		return nil, errors.New("one of file, content or environment is required")
+	}
+}
+
+// List 获取所有 stack（docker stack ls）
+func (receiver stack) List() (collections.List[StackVO], error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/services?filters={"label":["com.docker.stack.namespace"]}
+	filter := fmt.Sprintf(`{"label":["%s"]}`, StackNamespaceLabel)
+	services, err := UnixRequestDecode[[]ServiceInspectJson](context.Background(), receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services?filters="+url.QueryEscape(filter)), nil, nil)
+	if err != nil {
+		return collections.NewList[StackVO](), err
+	}
+
+	counts := map[string]int{}
+	for _, svc := range services {
+		counts[svc.Spec.Labels[StackNamespaceLabel]]++
+	}
+	lst := collections.NewList[StackVO]()
+	for _, name := range sortedKeys(counts) {
+		lst.Add(StackVO{Name: name, Services: counts[name]})
+	}
+	return lst, nil
+}
+
+// Services 获取 stack 下的服务（docker stack services）
+func (receiver stack) Services(namespace string) (collections.List[ServiceListVO], error) {
+	servicesUrl := receiver.api.URL("/services?status=true&filters=" + stackFilter(namespace))
+	services, err := UnixRequestDecode[collections.List[ServiceListVO]](context.Background(), receiver.api.httpClient, http.MethodGet, servicesUrl, nil, nil)
+	if err != nil {
+		return collections.NewList[ServiceListVO](), err
+	}
+	services.Foreach(func(svc *ServiceListVO) {
+		svc.Spec.TaskTemplate.ContainerSpec.Image = TrimImageDigest(svc.Spec.TaskTemplate.ContainerSpec.Image) // 去掉 digest 部分
+	})
+	return services, nil
+}
+
+// PS 获取 stack 下所有服务的实例（docker stack ps）
+func (receiver stack) PS(namespace string) (collections.List[ServiceTaskVO], error) {
+	lst := collections.NewList[ServiceTaskVO]()
+	services, err := receiver.Services(namespace)
+	if err != nil {
+		return lst, err
+	}
+
+	lstNode := node{api: receiver.api}.List()
+	svc := service{api: receiver.api}
+	services.Foreach(func(item *ServiceListVO) {
+		lst.AddList(svc.PS(lstNode, item.Spec.Name))
+	})
+	return lst, nil
+}
+
+// Remove 删除 stack：服务、密钥、配置、网络（docker stack rm）
+func (receiver stack) Remove(namespace string) error {
+	ctx := context.Background()
+	services, err := receiver.services(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	found := len(services) > 0
+	sort.Slice(services, func(i, j int) bool { return services[i].Spec.Name < services[j].Spec.Name })
+	for _, svc := range services {
+		if err = (service{api: receiver.api}).Delete(svc.ID); err != nil {
+			errs = append(errs, fmt.Errorf("remove service %s failed: %w", svc.Spec.Name, err))
+		}
+	}
+
+	for _, resource := range []string{"secrets", "configs", "networks"} {
+		objects, err := receiver.objects(ctx, resource, namespace)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		found = found || len(objects) > 0
+		for _, object := range objects {
+			if _, err = UnixDelete(receiver.api.httpClient, receiver.api.URL(fmt.Sprintf("/%s/%s", resource, object.ID))); err != nil {
+				errs = append(errs, fmt.Errorf("remove %s %s failed: %w", resource, object.ID, err))
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("nothing found in stack: %s", namespace)
+	}
+	return errors.Join(errs...)
+}
+
+// services 获取 stack 下的服务详情
+func (receiver stack) services(ctx context.Context, namespace string) ([]ServiceInspectJson, error) {
+	return UnixRequestDecode[[]ServiceInspectJson](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services?filters="+stackFilter(namespace)), nil, nil)
+}
+
+// objects 获取 stack 下的网络、配置、密钥
+func (receiver stack) objects(ctx context.Context, resource string, namespace string) ([]stackObject, error) {
+	return UnixRequestDecode[[]stackObject](ctx, receiver.api.httpClient, http.MethodGet, receiver.api.URL(fmt.Sprintf("/%s?filters=%s", resource, stackFilter(namespace))), nil, nil)
+}
+
+// stackFilter 按 com.docker.stack.namespace 标签过滤
+func stackFilter(namespace string) string {
+	filter := fmt.Sprintf(`{"label":["%s=%s"]}`, StackNamespaceLabel, namespace)
+	return url.QueryEscape(filter)
+}
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStackDeployUnchanged(t *testing.T) {
+	compose, err := ParseCompose([]byte(`
+services:
+  api:
+    image: farseer/fops:v1
+    environment: [APP=fops]
+  web:
+    image: nginx:1.25
+`), ComposeLoadOptions{Env: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, err := compose.ServiceSpecs("fops")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 当前服务：api 与 compose 一致（镜像已固定 digest），web 的镜像不同
+	current := map[string]ServiceSpec{}
+	for _, spec := range specs {
+		current[spec.Name] = spec
+	}
+	api := current["fops_api"]
+	api.TaskTemplate.ContainerSpec.Image = "farseer/fops:v1@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	current["fops_api"] = api
+	web := current["fops_web"]
+	web.TaskTemplate.ContainerSpec.Image = "nginx:1.24"
+	current["fops_web"] = web
+
+	var updated []string
+	client := stack{api: newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/services/")
+		switch {
+		case r.URL.Path == "/networks":
+			json.NewEncoder(w).Encode([]map[string]any{{"ID": "n1", "Name": "fops_default"}})
+		case r.URL.Path == "/services":
+			var services []map[string]any
+			for _, name := range []string{"fops_api", "fops_web"} {
+				services = append(services, map[string]any{"ID": name, "Version": map[string]int{"Index": 1}, "Spec": current[name]})
+			}
+			json.NewEncoder(w).Encode(services)
+		case r.Method == http.MethodGet && current[name].Name != "":
+			json.NewEncoder(w).Encode(map[string]any{"ID": name, "Version": map[string]int{"Index": 1}, "Spec": current[name]})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/update"):
+			updated = append(updated, strings.TrimSuffix(name, "/update"))
+			w.Write([]byte("{}"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})}
+
+	result, err := client.Deploy(context.Background(), "fops", compose, StackDeployOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有变化的服务不提交更新
+	if !reflect.DeepEqual(result.Updated, []string{"fops_web"}) || !reflect.DeepEqual(result.Unchanged, []string{"fops_api"}) || !reflect.DeepEqual(updated, []string{"fops_web"}) {
+		t.Fatalf("updated = %v, unchanged = %v, requests = %v", result.Updated, result.Unchanged, updated)
+	}
+}
@@ -1,15 +1,42 @@
 package docker
 
 import (
-	"strings"
-
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/farseer-go/collections"
 )
 
 type task struct {
 	api *dockerAPI
 }
 
+// TaskListOptions 查询任务的过滤条件，为空的条件不过滤
+type TaskListOptions struct {
+	Service      string            // 服务名称或ID
+	Node         string            // 节点名称或ID
+	DesiredState string            // 目标状态 running shutdown accepted
+	Labels       map[string]string // 任务标签，value 为空时只要求存在该标签
+}
+
+// ServiceTaskSummary 服务的任务概况
+type ServiceTaskSummary struct {
+	ServiceID   string    // 服务ID
+	ServiceName string    // 服务名称
+	Image       string    // 镜像
+	Running     int       // 运行中的任务数
+	Desired     int       // 期望的任务数
+	Failed      int       // 保留的历史任务中失败（failed、rejected）的数量
+	LastError   string    // 最近一次失败的错误信息
+	LastErrorAt time.Time // 最近一次失败的时间
+	Healthy     bool      // 运行中的任务数是否达到期望
+}
+
 // Inspect 查看服务详情
 // 注意：由于 Service 本身不包含 ContainerID，此方法实际上是通过 Service ID 查询其关联的 Task 列表
 func (receiver task) Inspect(taskId string) (ServiceIdInspectJson, error) {
@@ -27,7 +54,120 @@ func (receiver task) Inspect(taskId string) (ServiceIdInspectJson, error) {
 		task.Status.ContainerStatus.ContainerID = task.Status.ContainerStatus.ContainerID[:12]
 	}
 
-	task.Spec.ContainerSpec.Image = strings.Split(task.Spec.ContainerSpec.Image, "@")[0] // 去掉 digest 部分
+	task.Spec.ContainerSpec.Image = TrimImageDigest(task.Spec.ContainerSpec.Image) // 去掉 digest 部分
 
 	return task, nil
 }
+
+// List 查询集群中的任务，并补全节点名称和IP
+func (receiver task) List(options TaskListOptions) (collections.List[ServiceIdInspectJson], error) {
+	// curl --unix-socket /var/run/docker.sock http://localhost/tasks?filters={"desired-state":{"running":true}}
+	filters := map[string]map[string]bool{}
+	addFilter := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if filters[key] == nil {
+			filters[key] = map[string]bool{}
+		}
+		filters[key][value] = true
+	}
+	addFilter("service", options.Service)
+	addFilter("node", options.Node)
+	addFilter("desired-state", options.DesiredState)
+	for key, value := range options.Labels {
+		if value != "" {
+			key += "=" + value
+		}
+		addFilter("label", key)
+	}
+
+	tasksUrl := receiver.api.URL("/tasks")
+	if len(filters) > 0 {
+		filter, _ := json.Marshal(filters)
+		tasksUrl += "?filters=" + url.QueryEscape(string(filter))
+	}
+	tasks, err := UnixRequestDecode[collections.List[ServiceIdInspectJson]](context.Background(), receiver.api.httpClient, http.MethodGet, tasksUrl, nil, nil)
+	if err != nil {
+		return collections.NewList[ServiceIdInspectJson](), err
+	}
+
+	// 节点只查询一次，按ID建立索引
+	nodes := map[string]DockerNodeVO{}
+	node{api: receiver.api}.List().Foreach(func(item *DockerNodeVO) {
+		nodes[item.ID] = *item
+	})
+	tasks.Foreach(func(item *ServiceIdInspectJson) {
+		if curNode, exists := nodes[item.NodeID]; exists {
+			item.NodeName = curNode.Description.Hostname
+			item.NodeIP = curNode.Status.Addr
+		}
+		// 截断 ContainerID，保持和 CLI 输出一致
+		if len(item.Status.ContainerStatus.ContainerID) >= 12 {
+			item.Status.ContainerStatus.ContainerID = item.Status.ContainerStatus.ContainerID[:12]
+		}
+		item.Spec.ContainerSpec.Image = TrimImageDigest(item.Spec.ContainerSpec.Image) // 去掉 digest 部分
+	})
+	return tasks, nil
+}
+
+// Summary 所有服务的任务概况（运行数/期望数、失败的任务、最近一次错误），按服务名称排序
+func (receiver task) Summary() (collections.List[ServiceTaskSummary], error) {
+	services, err := UnixRequestDecode[collections.List[ServiceListVO]](context.Background(), receiver.api.httpClient, http.MethodGet, receiver.api.URL("/services?status=true"), nil, nil)
+	if err != nil {
+		return collections.NewList[ServiceTaskSummary](), err
+	}
+	tasks, err := UnixRequestDecode[[]ServiceIdInspectJson](context.Background(), receiver.api.httpClient, http.MethodGet, receiver.api.URL("/tasks"), nil, nil)
+	if err != nil {
+		return collections.NewList[ServiceTaskSummary](), err
+	}
+	return summarizeTasks(services, tasks), nil
+}
+
+func summarizeTasks(services collections.List[ServiceListVO], tasks []ServiceIdInspectJson) collections.List[ServiceTaskSummary] {
+	summaries := map[string]*ServiceTaskSummary{}
+	withStatus := map[string]bool{} // daemon 是否返回了 ServiceStatus（API 1.41+）
+	global := map[string]bool{}
+	services.Foreach(func(svc *ServiceListVO) {
+		summary := &ServiceTaskSummary{ServiceID: svc.ID, ServiceName: svc.Spec.Name, Image: TrimImageDigest(svc.Spec.TaskTemplate.ContainerSpec.Image)}
+		if svc.ServiceStatus != nil {
+			summary.Running, summary.Desired = svc.ServiceStatus.RunningTasks, svc.ServiceStatus.DesiredTasks
+			withStatus[svc.ID] = true
+		} else if svc.Spec.Mode.Global == nil {
+			summary.Desired = svc.Spec.Mode.Replicated.Replicas
+		} else {
+			global[svc.ID] = true
+		}
+		summaries[svc.ID] = summary
+	})
+
+	for _, item := range tasks {
+		summary, exists := summaries[item.ServiceID]
+		if !exists {
+			continue
+		}
+		switch item.Status.State {
+		case "running":
+			if !withStatus[item.ServiceID] && item.DesiredState == "running" {
+				summary.Running++
+			}
+		case "failed", "rejected":
+			summary.Failed++
+			if item.Status.Timestamp.After(summary.LastErrorAt) {
+				summary.LastError, summary.LastErrorAt = item.Status.Err, item.Status.Timestamp
+			}
+		}
+		// 旧版本 daemon 的全局服务以期望运行的任务数为准
+		if global[item.ServiceID] && item.DesiredState == "running" {
+			summary.Desired++
+		}
+	}
+
+	result := make([]ServiceTaskSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		summary.Healthy = summary.Running >= summary.Desired
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ServiceName < result[j].ServiceName })
+	return collections.NewList(result...)
+}
@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/farseer-go/collections"
+)
+
+func TestSummarizeTasks(t *testing.T) {
+	var services []ServiceListVO
+	json.Unmarshal([]byte(`[
+		{"ID": "s1", "Spec": {"Name": "web", "Mode": {"Replicated": {"Replicas": 3}}, "TaskTemplate": {"ContainerSpec": {"Image": "nginx:1.25@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}}}},
+		{"ID": "s2", "Spec": {"Name": "agent", "Mode": {"Global": {}}, "TaskTemplate": {"ContainerSpec": {"Image": "agent:v1"}}}},
+		{"ID": "s3", "Spec": {"Name": "api", "Mode": {"Replicated": {"Replicas": 2}}}, "ServiceStatus": {"RunningTasks": 2, "DesiredTasks": 2}}
+	]`), &services)
+	var tasks []ServiceIdInspectJson
+	json.Unmarshal([]byte(`[
+		{"ServiceID": "s1", "DesiredState": "running", "Status": {"State": "running"}},
+		{"ServiceID": "s1", "DesiredState": "running", "Status": {"State": "starting"}},
+		{"ServiceID": "s1", "DesiredState": "shutdown", "Status": {"State": "failed", "Err": "old error", "Timestamp": "2024-01-01T00:00:00Z"}},
+		{"ServiceID": "s1", "DesiredState": "shutdown", "Status": {"State": "rejected", "Err": "no suitable node", "Timestamp": "2024-01-02T00:00:00Z"}},
+		{"ServiceID": "s2", "DesiredState": "running", "Status": {"State": "running"}},
+		{"ServiceID": "s2", "DesiredState": "running", "Status": {"State": "running"}},
+		{"ServiceID": "s2", "DesiredState": "shutdown", "Status": {"State": "shutdown"}},
+		{"ServiceID": "s3", "DesiredState": "running", "Status": {"State": "running"}},
+		{"ServiceID": "unknown", "DesiredState": "running", "Status": {"State": "running"}}
+	]`), &tasks)
+
+	summaries := summarizeTasks(collections.NewList(services...), tasks).ToArray()
+	want := []ServiceTaskSummary{
+		{ServiceID: "s2", ServiceName: "agent", Image: "agent:v1", Running: 2, Desired: 2, Healthy: true},
+		{ServiceID: "s3", ServiceName: "api", Running: 2, Desired: 2, Healthy: true},
+		{ServiceID: "s1", ServiceName: "web", Image: "nginx:1.25", Running: 1, Desired: 3, Failed: 2, LastError: "no suitable node"},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("summarizeTasks() = %#v", summaries)
+	}
+	for i, summary := range summaries {
+		summary.LastErrorAt = want[i].LastErrorAt
+		if summary != want[i] {
+			t.Errorf("summarizeTasks()[%d] = %#v, want %#v", i, summary, want[i])
+		}
+	}
+}
+
+func TestTaskListAPIError(t *testing.T) {
+	api := newTestDockerAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"message": "This node is not a swarm manager."})
+	})
+
+	_, err := task{api: api}.List(TaskListOptions{Service: "fops"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "This node is not a swarm manager." {
+		t.Fatalf("List() error = %#v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package docker
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 // UnixGetDecode 通过Unix Socket发送HTTP请求，并将响应解析为指定类型
@@ -91,3 +94,72 @@ func UnixDelete(unixClient *http.Client, url string) (*http.Response, error) {
 	body, _ := io.ReadAll(resp.Body)
 	return resp, fmt.Errorf("delete failed (%d): %s", resp.StatusCode, string(body))
 }
+
+// APIError docker daemon 返回的错误（非 2xx）
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Message    string // daemon 返回的 message
+}
+
+func (receiver *APIError) Error() string {
+	return fmt.Sprintf("request failed (%d): %s", receiver.StatusCode, receiver.Message)
+}
+
+// IsNotFound 资源不存在
+func (receiver *APIError) IsNotFound() bool {
+	return receiver.StatusCode == http.StatusNotFound
+}
+
+// newAPIError 读取响应中的错误信息 {"message": "..."}
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	var result struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &result) != nil || result.Message == "" {
+		result.Message = strings.TrimSpace(string(body))
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: result.Message}
+}
+
+// UnixPostJsonDecode 发送带 JSON Body 的POST请求，并将响应解析为指定类型，非 2xx 时返回 *APIError
+func UnixPostJsonDecode[T any](unixClient *http.Client, url string, body any, headers map[string]string) (T, error) {
+	return UnixRequestDecode[T](context.Background(), unixClient, http.MethodPost, url, body, headers)
+}
+
+// UnixRequestDecode 发送请求（body 不为 nil 时以 JSON 发送），并将响应解析为指定类型，非 2xx 时返回 *APIError
+func UnixRequestDecode[T any](ctx context.Context, unixClient *http.Client, method string, url string, body any, headers map[string]string) (T, error) {
+	var t T
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return t, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return t, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := unixClient.Do(req)
+	if err != nil {
+		return t, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return t, newAPIError(resp)
+	}
+
+	json.NewDecoder(resp.Body).Decode(&t)
+	return t, nil
+}